@@ -2,6 +2,7 @@ package ast
 
 import (
 	"github.com/grzkv/m-interpreter/token"
+	"math/big"
 	"strings"
 )
 
@@ -50,6 +51,7 @@ func (p *Program) String() string {
 type LetSt struct {
 	Token token.Token // always LET
 	Ident *IdentifierEx
+	Type  *TypeExpr // nil if not annotated
 	Expr  ExprNode
 }
 
@@ -65,7 +67,11 @@ func (s *LetSt) String() string {
 	var b strings.Builder
 
 	b.WriteString("let ")
-	b.WriteString(s.Ident.String() + " = ")
+	b.WriteString(s.Ident.String())
+	if s.Type != nil {
+		b.WriteString(": " + s.Type.String())
+	}
+	b.WriteString(" = ")
 	b.WriteString(s.Expr.String() + ";")
 
 	return b.String()
@@ -126,10 +132,12 @@ func (e *IdentifierEx) String() string {
 	return e.Value
 }
 
-// IntegerLiteralEx is an integer value
+// IntegerLiteralEx is an integer value. Values that do not fit int64
+// are in Big, Value is 0 then
 type IntegerLiteralEx struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int
 }
 
 // TokenLiteral makes integer literal a Node
@@ -144,6 +152,40 @@ func (expr *IntegerLiteralEx) String() string {
 
 func (expr *IntegerLiteralEx) expr() {}
 
+// FloatLiteral is a floating point value, e.g. 3.14, 1e-9
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+// TokenLiteral makes float literal a Node
+func (expr *FloatLiteral) TokenLiteral() string {
+	return expr.Token.Literal
+}
+
+func (expr *FloatLiteral) String() string {
+	return expr.Token.Literal
+}
+
+func (expr *FloatLiteral) expr() {}
+
+// BooleanLiteral is true or false
+type BooleanLiteral struct {
+	Token token.Token
+	Value bool
+}
+
+// TokenLiteral makes boolean literal a Node
+func (expr *BooleanLiteral) TokenLiteral() string {
+	return expr.Token.Literal
+}
+
+func (expr *BooleanLiteral) String() string {
+	return expr.Token.Literal
+}
+
+func (expr *BooleanLiteral) expr() {}
+
 // PrefixExpr represents e.g. !true, -(a+b), -5, etc.
 type PrefixExpr struct {
 	Token token.Token
@@ -167,9 +209,9 @@ func (expr *PrefixExpr) expr() {}
 // InfixExpr represents *1+1*, *(3-2) + (a * b)*, etc.
 type InfixExpr struct {
 	OpToken token.Token
-	Left ExprNode
-	Op string
-	Right ExprNode
+	Left    ExprNode
+	Op      string
+	Right   ExprNode
 }
 
 // TokenLiteral makes InfixExpr a Node
@@ -189,4 +231,233 @@ func (expr *InfixExpr) String() string {
 	return b.String()
 }
 
-func (expr *InfixExpr) expr() {}
\ No newline at end of file
+func (expr *InfixExpr) expr() {}
+
+// TypeExpr is a type annotation. The token tells the kind of the type:
+// IDENT for named types like *int*, LBRACKET for arrays like *[int]*,
+// LBRACE for hashes like *{string: int}* and FUNCTION for functions
+// like *fn(int, string) -> bool*
+type TypeExpr struct {
+	Token token.Token
+	// Name of a named type
+	Name string
+	// Args are the element type of an array, the key and value types
+	// of a hash, the parameter types and the result type of a function
+	Args []*TypeExpr
+}
+
+// TokenLiteral makes TypeExpr a Node
+func (t *TypeExpr) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *TypeExpr) String() string {
+	args := make([]string, len(t.Args))
+	for i, a := range t.Args {
+		args[i] = a.String()
+	}
+
+	switch t.Token.Typ {
+	case token.LBRACKET:
+		return "[" + strings.Join(args, "") + "]"
+	case token.LBRACE:
+		return "{" + strings.Join(args, ": ") + "}"
+	case token.FUNCTION:
+		if len(args) == 0 {
+			return "fn() -> ?"
+		}
+		return "fn(" + strings.Join(args[:len(args)-1], ", ") + ") -> " + args[len(args)-1]
+	}
+
+	return t.Name
+}
+
+// BlockSt is a sequence of statements in braces, e.g. a loop body
+type BlockSt struct {
+	Token   token.Token // always {
+	StNodes []Node
+	Rbrace  token.Token // always }
+}
+
+// TokenLiteral makes BlockSt a Node
+func (s *BlockSt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *BlockSt) statement() {}
+
+func (s *BlockSt) String() string {
+	var b strings.Builder
+
+	b.WriteString("{")
+	for _, st := range s.StNodes {
+		b.WriteString(" " + st.String())
+	}
+	b.WriteString(" }")
+
+	return b.String()
+}
+
+// WhileSt is *while (cond) { ... }*
+type WhileSt struct {
+	Token token.Token // always WHILE
+	Cond  ExprNode
+	Body  *BlockSt
+}
+
+// TokenLiteral makes WhileSt a Node
+func (s *WhileSt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *WhileSt) statement() {}
+
+func (s *WhileSt) String() string {
+	return "while " + s.Cond.String() + " " + s.Body.String()
+}
+
+// ForSt is *for (x in collection) { ... }*
+type ForSt struct {
+	Token      token.Token // always FOR
+	Ident      *IdentifierEx
+	Collection ExprNode
+	Body       *BlockSt
+}
+
+// TokenLiteral makes ForSt a Node
+func (s *ForSt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *ForSt) statement() {}
+
+func (s *ForSt) String() string {
+	return "for (" + s.Ident.String() + " in " + s.Collection.String() + ") " + s.Body.String()
+}
+
+// BreakSt is *break;*
+type BreakSt struct {
+	Token token.Token // always BREAK
+}
+
+// TokenLiteral makes BreakSt a Node
+func (s *BreakSt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *BreakSt) statement() {}
+
+func (s *BreakSt) String() string {
+	return "break;"
+}
+
+// ContinueSt is *continue;*
+type ContinueSt struct {
+	Token token.Token // always CONTINUE
+}
+
+// TokenLiteral makes ContinueSt a Node
+func (s *ContinueSt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *ContinueSt) statement() {}
+
+func (s *ContinueSt) String() string {
+	return "continue;"
+}
+
+// Pos returns the position of the first token of the node. Parentheses
+// leave no trace in the tree, so for expressions it can be after
+// an opening one
+func Pos(n Node) token.Pos {
+	switch n := n.(type) {
+	case *Program:
+		if len(n.StNodes) != 0 {
+			return Pos(n.StNodes[0])
+		}
+	case *LetSt:
+		return n.Token.Pos
+	case *ReturnSt:
+		return n.RootToken.Pos
+	case *ExpressionSt:
+		return n.RootToken.Pos
+	case *IdentifierEx:
+		return n.Token.Pos
+	case *IntegerLiteralEx:
+		return n.Token.Pos
+	case *FloatLiteral:
+		return n.Token.Pos
+	case *BooleanLiteral:
+		return n.Token.Pos
+	case *PrefixExpr:
+		return n.Token.Pos
+	case *InfixExpr:
+		if n.Left != nil {
+			return Pos(n.Left)
+		}
+		return n.OpToken.Pos
+	case *IndexExpr:
+		if n.Left != nil {
+			return Pos(n.Left)
+		}
+		return n.Token.Pos
+	case *AssignExpr:
+		if n.Target != nil {
+			return Pos(n.Target)
+		}
+		return n.Token.Pos
+	case *TypeExpr:
+		return n.Token.Pos
+	case *BlockSt:
+		return n.Token.Pos
+	case *WhileSt:
+		return n.Token.Pos
+	case *ForSt:
+		return n.Token.Pos
+	case *BreakSt:
+		return n.Token.Pos
+	case *ContinueSt:
+		return n.Token.Pos
+	}
+
+	return token.Pos{}
+}
+
+// IndexExpr is *a[i]*
+type IndexExpr struct {
+	Token token.Token // always [
+	Left  ExprNode
+	Index ExprNode
+}
+
+// TokenLiteral makes IndexExpr a Node
+func (expr *IndexExpr) TokenLiteral() string {
+	return expr.Token.Literal
+}
+
+func (expr *IndexExpr) String() string {
+	return "(" + expr.Left.String() + "[" + expr.Index.String() + "])"
+}
+
+func (expr *IndexExpr) expr() {}
+
+// AssignExpr is an assignment to a name or an element, e.g. *x = 1*,
+// *a[i] += 2*. The value of the expression is the assigned value
+type AssignExpr struct {
+	Token  token.Token // the operator
+	Target ExprNode    // *IdentifierEx or *IndexExpr
+	Op     string
+	Value  ExprNode
+}
+
+// TokenLiteral makes AssignExpr a Node
+func (expr *AssignExpr) TokenLiteral() string {
+	return expr.Token.Literal
+}
+
+func (expr *AssignExpr) String() string {
+	return "(" + expr.Target.String() + " " + expr.Op + " " + expr.Value.String() + ")"
+}
+
+func (expr *AssignExpr) expr() {}
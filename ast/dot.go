@@ -0,0 +1,115 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToDOT renders the tree as a Graphviz graph. Every node is a vertex labeled
+// with the node type and its operator, name or value. Edges are labeled with
+// the names of the fields that hold the children
+func ToDOT(n Node) string {
+	d := dotWriter{}
+
+	d.b.WriteString("digraph AST {\n")
+	d.b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	d.node(n)
+	d.b.WriteString("}\n")
+
+	return d.b.String()
+}
+
+type dotWriter struct {
+	b    strings.Builder
+	next int
+}
+
+// node writes the vertex for n and its subtree. Returns the vertex id
+func (d *dotWriter) node(n Node) string {
+	id := fmt.Sprintf("n%d", d.next)
+	d.next++
+
+	label := fmt.Sprintf("%T", n)
+	label = label[strings.LastIndex(label, ".")+1:]
+
+	var children []dotEdge
+
+	switch n := n.(type) {
+	case *Program:
+		for i, st := range n.StNodes {
+			children = append(children, dotEdge{fmt.Sprintf("StNodes[%d]", i), st})
+		}
+	case *LetSt:
+		children = append(children, dotEdge{"Ident", n.Ident}, dotEdge{"Type", n.Type}, dotEdge{"Expr", n.Expr})
+	case *ReturnSt:
+		children = append(children, dotEdge{"Expr", n.Expr})
+	case *ExpressionSt:
+		children = append(children, dotEdge{"Expr", n.Expr})
+	case *IdentifierEx:
+		label += "\n" + n.Value
+	case *IntegerLiteralEx, *FloatLiteral, *BooleanLiteral:
+		label += "\n" + n.TokenLiteral()
+	case *PrefixExpr:
+		label += "\n" + n.Op
+		children = append(children, dotEdge{"Right", n.Right})
+	case *InfixExpr:
+		label += "\n" + n.Op
+		children = append(children, dotEdge{"Left", n.Left}, dotEdge{"Right", n.Right})
+	case *IndexExpr:
+		children = append(children, dotEdge{"Left", n.Left}, dotEdge{"Index", n.Index})
+	case *AssignExpr:
+		label += "\n" + n.Op
+		children = append(children, dotEdge{"Target", n.Target}, dotEdge{"Value", n.Value})
+	case *TypeExpr:
+		label += "\n" + n.String()
+	case *BlockSt:
+		for i, st := range n.StNodes {
+			children = append(children, dotEdge{fmt.Sprintf("StNodes[%d]", i), st})
+		}
+	case *WhileSt:
+		children = append(children, dotEdge{"Cond", n.Cond}, dotEdge{"Body", n.Body})
+	case *ForSt:
+		children = append(children, dotEdge{"Ident", n.Ident}, dotEdge{"Collection", n.Collection}, dotEdge{"Body", n.Body})
+	}
+
+	fmt.Fprintf(&d.b, "\t%s [label=%s];\n", id, dotQuote(label))
+
+	for _, c := range children {
+		if isNilNode(c.node) {
+			continue
+		}
+		childID := d.node(c.node)
+		fmt.Fprintf(&d.b, "\t%s -> %s [label=%s];\n", id, childID, dotQuote(c.field))
+	}
+
+	return id
+}
+
+type dotEdge struct {
+	field string
+	node  Node
+}
+
+// isNilNode tells nil interfaces and typed nil pointers
+// which a failed parse can leave in the tree
+func isNilNode(n Node) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *IdentifierEx:
+		return n == nil
+	case *TypeExpr:
+		return n == nil
+	case *BlockSt:
+		return n == nil
+	}
+	return false
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+
+	return `"` + s + `"`
+}
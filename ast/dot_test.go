@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+func TestToDOT(t *testing.T) {
+	// a - b * 2
+	expr := &InfixExpr{
+		OpToken: token.Token{Typ: token.MINUS, Literal: "-"},
+		Left:    &IdentifierEx{Token: token.Token{Typ: token.IDENT, Literal: "a"}, Value: "a"},
+		Op:      "-",
+		Right: &InfixExpr{
+			OpToken: token.Token{Typ: token.MULT, Literal: "*"},
+			Left:    &IdentifierEx{Token: token.Token{Typ: token.IDENT, Literal: "b"}, Value: "b"},
+			Op:      "*",
+			Right:   &IntegerLiteralEx{Token: token.Token{Typ: token.INT, Literal: "2"}, Value: 2},
+		},
+	}
+
+	prg := &Program{StNodes: []Node{&ExpressionSt{Expr: expr}}}
+
+	const expected = `digraph AST {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Program"];
+	n1 [label="ExpressionSt"];
+	n2 [label="InfixExpr\n-"];
+	n3 [label="IdentifierEx\na"];
+	n2 -> n3 [label="Left"];
+	n4 [label="InfixExpr\n*"];
+	n5 [label="IdentifierEx\nb"];
+	n4 -> n5 [label="Left"];
+	n6 [label="IntegerLiteralEx\n2"];
+	n4 -> n6 [label="Right"];
+	n2 -> n4 [label="Right"];
+	n1 -> n2 [label="Expr"];
+	n0 -> n1 [label="StNodes[0]"];
+}
+`
+
+	if got := ToDOT(prg); got != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,488 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// JSON form of the AST. Every node is an object with the "kind" field holding
+// the node type name. The other fields are the node fields in lower camel
+// case. Tokens keep their type, literal and position
+
+// MarshalJSON makes JSON of the whole program
+func (p *Program) MarshalJSON() ([]byte, error) {
+	sts := p.StNodes
+	if sts == nil {
+		sts = []Node{}
+	}
+
+	return json.Marshal(struct {
+		Kind    string `json:"kind"`
+		StNodes []Node `json:"stNodes"`
+	}{"Program", sts})
+}
+
+// MarshalJSON makes JSON of the let statement
+func (s *LetSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string        `json:"kind"`
+		Token token.Token   `json:"token"`
+		Ident *IdentifierEx `json:"ident"`
+		Type  *TypeExpr     `json:"type,omitempty"`
+		Expr  ExprNode      `json:"expr"`
+	}{"LetSt", s.Token, s.Ident, s.Type, s.Expr})
+}
+
+// MarshalJSON makes JSON of the return statement
+func (s *ReturnSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind      string      `json:"kind"`
+		RootToken token.Token `json:"rootToken"`
+		Expr      ExprNode    `json:"expr"`
+	}{"ReturnSt", s.RootToken, s.Expr})
+}
+
+// MarshalJSON makes JSON of the expression statement
+func (s *ExpressionSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind      string      `json:"kind"`
+		RootToken token.Token `json:"rootToken"`
+		Expr      ExprNode    `json:"expr"`
+	}{"ExpressionSt", s.RootToken, s.Expr})
+}
+
+// MarshalJSON makes JSON of the identifier
+func (e *IdentifierEx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Value string      `json:"value"`
+	}{"IdentifierEx", e.Token, e.Value})
+}
+
+// MarshalJSON makes JSON of the integer literal. Big values are
+// JSON numbers too
+func (expr *IntegerLiteralEx) MarshalJSON() ([]byte, error) {
+	var value interface{} = expr.Value
+	if expr.Big != nil {
+		value = expr.Big
+	}
+
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Value interface{} `json:"value"`
+	}{"IntegerLiteralEx", expr.Token, value})
+}
+
+// MarshalJSON makes JSON of the float literal
+func (expr *FloatLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Value float64     `json:"value"`
+	}{"FloatLiteral", expr.Token, expr.Value})
+}
+
+// MarshalJSON makes JSON of the boolean literal
+func (expr *BooleanLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Value bool        `json:"value"`
+	}{"BooleanLiteral", expr.Token, expr.Value})
+}
+
+// MarshalJSON makes JSON of the prefix expression
+func (expr *PrefixExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Op    string      `json:"op"`
+		Right ExprNode    `json:"right"`
+	}{"PrefixExpr", expr.Token, expr.Op, expr.Right})
+}
+
+// MarshalJSON makes JSON of the infix expression
+func (expr *InfixExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind    string      `json:"kind"`
+		OpToken token.Token `json:"opToken"`
+		Left    ExprNode    `json:"left"`
+		Op      string      `json:"op"`
+		Right   ExprNode    `json:"right"`
+	}{"InfixExpr", expr.OpToken, expr.Left, expr.Op, expr.Right})
+}
+
+// MarshalJSON makes JSON of the type annotation
+func (t *TypeExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Name  string      `json:"name,omitempty"`
+		Args  []*TypeExpr `json:"args,omitempty"`
+	}{"TypeExpr", t.Token, t.Name, t.Args})
+}
+
+// MarshalJSON makes JSON of the block
+func (s *BlockSt) MarshalJSON() ([]byte, error) {
+	sts := s.StNodes
+	if sts == nil {
+		sts = []Node{}
+	}
+
+	return json.Marshal(struct {
+		Kind    string      `json:"kind"`
+		Token   token.Token `json:"token"`
+		StNodes []Node      `json:"stNodes"`
+		Rbrace  token.Token `json:"rbrace"`
+	}{"BlockSt", s.Token, sts, s.Rbrace})
+}
+
+// MarshalJSON makes JSON of the while loop
+func (s *WhileSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Cond  ExprNode    `json:"cond"`
+		Body  *BlockSt    `json:"body"`
+	}{"WhileSt", s.Token, s.Cond, s.Body})
+}
+
+// MarshalJSON makes JSON of the for loop
+func (s *ForSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind       string        `json:"kind"`
+		Token      token.Token   `json:"token"`
+		Ident      *IdentifierEx `json:"ident"`
+		Collection ExprNode      `json:"collection"`
+		Body       *BlockSt      `json:"body"`
+	}{"ForSt", s.Token, s.Ident, s.Collection, s.Body})
+}
+
+// MarshalJSON makes JSON of the break statement
+func (s *BreakSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+	}{"BreakSt", s.Token})
+}
+
+// MarshalJSON makes JSON of the continue statement
+func (s *ContinueSt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+	}{"ContinueSt", s.Token})
+}
+
+// MarshalJSON makes JSON of the index expression
+func (expr *IndexExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Token token.Token `json:"token"`
+		Left  ExprNode    `json:"left"`
+		Index ExprNode    `json:"index"`
+	}{"IndexExpr", expr.Token, expr.Left, expr.Index})
+}
+
+// MarshalJSON makes JSON of the assignment
+func (expr *AssignExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind   string      `json:"kind"`
+		Token  token.Token `json:"token"`
+		Target ExprNode    `json:"target"`
+		Op     string      `json:"op"`
+		Value  ExprNode    `json:"value"`
+	}{"AssignExpr", expr.Token, expr.Target, expr.Op, expr.Value})
+}
+
+// jsonNode has the fields of all the node kinds
+type jsonNode struct {
+	Kind string `json:"kind"`
+
+	Token     token.Token `json:"token"`
+	RootToken token.Token `json:"rootToken"`
+	OpToken   token.Token `json:"opToken"`
+
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value"`
+
+	Ident json.RawMessage `json:"ident"`
+	Type  json.RawMessage `json:"type"`
+	Expr  json.RawMessage `json:"expr"`
+	Left  json.RawMessage `json:"left"`
+	Right json.RawMessage `json:"right"`
+
+	StNodes []json.RawMessage `json:"stNodes"`
+
+	Name string            `json:"name"`
+	Args []json.RawMessage `json:"args"`
+
+	Cond       json.RawMessage `json:"cond"`
+	Collection json.RawMessage `json:"collection"`
+	Body       json.RawMessage `json:"body"`
+	Rbrace     token.Token     `json:"rbrace"`
+
+	Index  json.RawMessage `json:"index"`
+	Target json.RawMessage `json:"target"`
+}
+
+// UnmarshalProgram restores the program from its JSON form
+func UnmarshalProgram(data []byte) (*Program, error) {
+	n, err := UnmarshalNode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	prg, ok := n.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("expected Program, got %T", n)
+	}
+
+	return prg, nil
+}
+
+// UnmarshalNode restores a node of any kind from its JSON form.
+// JSON null gives nil
+func UnmarshalNode(data []byte) (Node, error) {
+	var jn *jsonNode
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return nil, err
+	}
+	if jn == nil {
+		return nil, nil
+	}
+
+	switch jn.Kind {
+	case "Program":
+		sts, err := unmarshalStatements(jn.StNodes)
+		if err != nil {
+			return nil, err
+		}
+		return &Program{StNodes: sts}, nil
+	case "BlockSt":
+		sts, err := unmarshalStatements(jn.StNodes)
+		if err != nil {
+			return nil, err
+		}
+		return &BlockSt{Token: jn.Token, StNodes: sts, Rbrace: jn.Rbrace}, nil
+	case "WhileSt":
+		cond, err := unmarshalExpr(jn.Cond)
+		if err != nil {
+			return nil, err
+		}
+		body, err := unmarshalBlock(jn.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &WhileSt{Token: jn.Token, Cond: cond, Body: body}, nil
+	case "ForSt":
+		ident, err := unmarshalIdent(jn.Ident)
+		if err != nil {
+			return nil, err
+		}
+		coll, err := unmarshalExpr(jn.Collection)
+		if err != nil {
+			return nil, err
+		}
+		body, err := unmarshalBlock(jn.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ForSt{Token: jn.Token, Ident: ident, Collection: coll, Body: body}, nil
+	case "BreakSt":
+		return &BreakSt{Token: jn.Token}, nil
+	case "ContinueSt":
+		return &ContinueSt{Token: jn.Token}, nil
+	case "LetSt":
+		ident, err := unmarshalIdent(jn.Ident)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := unmarshalType(jn.Type)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := unmarshalExpr(jn.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &LetSt{Token: jn.Token, Ident: ident, Type: typ, Expr: expr}, nil
+	case "ReturnSt":
+		expr, err := unmarshalExpr(jn.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &ReturnSt{RootToken: jn.RootToken, Expr: expr}, nil
+	case "ExpressionSt":
+		expr, err := unmarshalExpr(jn.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &ExpressionSt{RootToken: jn.RootToken, Expr: expr}, nil
+	case "IdentifierEx":
+		e := &IdentifierEx{Token: jn.Token}
+		if err := json.Unmarshal(jn.Value, &e.Value); err != nil {
+			return nil, fmt.Errorf("bad IdentifierEx value: %v", err)
+		}
+		return e, nil
+	case "IntegerLiteralEx":
+		e := &IntegerLiteralEx{Token: jn.Token}
+		if err := json.Unmarshal(jn.Value, &e.Value); err == nil {
+			return e, nil
+		}
+		e.Big = new(big.Int)
+		if err := json.Unmarshal(jn.Value, e.Big); err != nil {
+			return nil, fmt.Errorf("bad IntegerLiteralEx value: %v", err)
+		}
+		return e, nil
+	case "FloatLiteral":
+		e := &FloatLiteral{Token: jn.Token}
+		if err := json.Unmarshal(jn.Value, &e.Value); err != nil {
+			return nil, fmt.Errorf("bad FloatLiteral value: %v", err)
+		}
+		return e, nil
+	case "BooleanLiteral":
+		e := &BooleanLiteral{Token: jn.Token}
+		if err := json.Unmarshal(jn.Value, &e.Value); err != nil {
+			return nil, fmt.Errorf("bad BooleanLiteral value: %v", err)
+		}
+		return e, nil
+	case "PrefixExpr":
+		right, err := unmarshalExpr(jn.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &PrefixExpr{Token: jn.Token, Op: jn.Op, Right: right}, nil
+	case "InfixExpr":
+		left, err := unmarshalExpr(jn.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := unmarshalExpr(jn.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &InfixExpr{OpToken: jn.OpToken, Left: left, Op: jn.Op, Right: right}, nil
+	case "IndexExpr":
+		left, err := unmarshalExpr(jn.Left)
+		if err != nil {
+			return nil, err
+		}
+		index, err := unmarshalExpr(jn.Index)
+		if err != nil {
+			return nil, err
+		}
+		return &IndexExpr{Token: jn.Token, Left: left, Index: index}, nil
+	case "AssignExpr":
+		target, err := unmarshalExpr(jn.Target)
+		if err != nil {
+			return nil, err
+		}
+		value, err := unmarshalExpr(jn.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &AssignExpr{Token: jn.Token, Target: target, Op: jn.Op, Value: value}, nil
+	case "TypeExpr":
+		t := &TypeExpr{Token: jn.Token, Name: jn.Name}
+		for _, raw := range jn.Args {
+			arg, err := unmarshalType(raw)
+			if err != nil {
+				return nil, err
+			}
+			t.Args = append(t.Args, arg)
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("unknown node kind %q", jn.Kind)
+}
+
+func unmarshalExpr(data json.RawMessage) (ExprNode, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	n, err := UnmarshalNode(data)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	expr, ok := n.(ExprNode)
+	if !ok {
+		return nil, fmt.Errorf("expected expression, got %T", n)
+	}
+
+	return expr, nil
+}
+
+func unmarshalIdent(data json.RawMessage) (*IdentifierEx, error) {
+	expr, err := unmarshalExpr(data)
+	if err != nil || expr == nil {
+		return nil, err
+	}
+
+	ident, ok := expr.(*IdentifierEx)
+	if !ok {
+		return nil, fmt.Errorf("expected IdentifierEx, got %T", expr)
+	}
+
+	return ident, nil
+}
+
+func unmarshalType(data json.RawMessage) (*TypeExpr, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	n, err := UnmarshalNode(data)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	t, ok := n.(*TypeExpr)
+	if !ok {
+		return nil, fmt.Errorf("expected TypeExpr, got %T", n)
+	}
+
+	return t, nil
+}
+
+func unmarshalStatements(raws []json.RawMessage) ([]Node, error) {
+	var sts []Node
+	for _, raw := range raws {
+		st, err := UnmarshalNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := st.(StNode); !ok {
+			return nil, fmt.Errorf("expected statement, got %T", st)
+		}
+		sts = append(sts, st)
+	}
+
+	return sts, nil
+}
+
+func unmarshalBlock(data json.RawMessage) (*BlockSt, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	n, err := UnmarshalNode(data)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	b, ok := n.(*BlockSt)
+	if !ok {
+		return nil, fmt.Errorf("expected BlockSt, got %T", n)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,181 @@
+package ast
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	ident := func(name string, col int) *IdentifierEx {
+		return &IdentifierEx{
+			Token: token.Token{Typ: token.IDENT, Literal: name, Pos: token.Pos{Line: 1, Col: col}},
+			Value: name,
+		}
+	}
+
+	prg := &Program{
+		StNodes: []Node{
+			&LetSt{
+				Token: token.Token{Typ: token.LET, Literal: "let", Pos: token.Pos{Line: 1, Col: 1}},
+				Ident: ident("a", 5),
+				Type: &TypeExpr{
+					Token: token.Token{Typ: token.LBRACKET, Literal: "[", Pos: token.Pos{Line: 1, Col: 8}},
+					Args: []*TypeExpr{{
+						Token: token.Token{Typ: token.IDENT, Literal: "int", Pos: token.Pos{Line: 1, Col: 9}},
+						Name:  "int",
+					}},
+				},
+				Expr: &InfixExpr{
+					OpToken: token.Token{Typ: token.PLUS, Literal: "+", Pos: token.Pos{Line: 1, Col: 12}},
+					Left: &PrefixExpr{
+						Token: token.Token{Typ: token.MINUS, Literal: "-", Pos: token.Pos{Line: 1, Col: 9}},
+						Op:    "-",
+						Right: ident("b", 10),
+					},
+					Op: "+",
+					Right: &IntegerLiteralEx{
+						Token: token.Token{Typ: token.INT, Literal: "42", Pos: token.Pos{Line: 1, Col: 14}},
+						Value: 42,
+					},
+				},
+			},
+			&ReturnSt{
+				RootToken: token.Token{Typ: token.RETURN, Literal: "return", Pos: token.Pos{Line: 2, Col: 1}},
+				Expr:      ident("a", 8),
+			},
+			&ExpressionSt{
+				RootToken: token.Token{Typ: token.IDENT, Literal: "a", Pos: token.Pos{Line: 3, Col: 1}},
+				Expr:      ident("a", 1),
+			},
+		},
+	}
+
+	data, err := json.Marshal(prg)
+	if err != nil {
+		t.Fatalf("Marshalling failed: %v", err)
+	}
+
+	for _, kind := range []string{"Program", "LetSt", "ReturnSt", "ExpressionSt", "IdentifierEx", "IntegerLiteralEx", "PrefixExpr", "InfixExpr", "TypeExpr"} {
+		if !strings.Contains(string(data), `"kind":"`+kind+`"`) {
+			t.Fatalf("Expected kind %s in %s", kind, data)
+		}
+	}
+
+	decoded, err := UnmarshalProgram(data)
+	if err != nil {
+		t.Fatalf("Unmarshalling failed: %v", err)
+	}
+
+	if decoded.String() != prg.String() {
+		t.Fatalf("Expected %q, got %q", prg.String(), decoded.String())
+	}
+
+	if !reflect.DeepEqual(decoded, prg) {
+		t.Fatalf("Decoded program differs from the original")
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []string{
+		`{"kind":"Nope"}`,
+		`{"kind":"LetSt","ident":{"kind":"IntegerLiteralEx","value":1}}`,
+		`{"kind":"Program","stNodes":[{"kind":"IdentifierEx","value":1}]}`,
+		`[]`,
+	}
+
+	for _, in := range tests {
+		if _, err := UnmarshalProgram([]byte(in)); err == nil {
+			t.Fatalf("Expected error decoding %s", in)
+		}
+	}
+}
+
+func TestJSONLoops(t *testing.T) {
+	x := &IdentifierEx{Token: token.Token{Typ: token.IDENT, Literal: "x"}, Value: "x"}
+
+	prg := &Program{
+		StNodes: []Node{
+			&ForSt{
+				Token:      token.Token{Typ: token.FOR, Literal: "for"},
+				Ident:      x,
+				Collection: x,
+				Body: &BlockSt{
+					Token: token.Token{Typ: token.LBRACE, Literal: "{"},
+					StNodes: []Node{
+						&WhileSt{
+							Token: token.Token{Typ: token.WHILE, Literal: "while"},
+							Cond:  x,
+							Body: &BlockSt{
+								Token:   token.Token{Typ: token.LBRACE, Literal: "{"},
+								StNodes: []Node{&BreakSt{Token: token.Token{Typ: token.BREAK, Literal: "break"}}},
+								Rbrace:  token.Token{Typ: token.RBRACE, Literal: "}"},
+							},
+						},
+						&ContinueSt{Token: token.Token{Typ: token.CONTINUE, Literal: "continue"}},
+					},
+					Rbrace: token.Token{Typ: token.RBRACE, Literal: "}"},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(prg)
+	if err != nil {
+		t.Fatalf("Marshalling failed: %v", err)
+	}
+
+	decoded, err := UnmarshalProgram(data)
+	if err != nil {
+		t.Fatalf("Unmarshalling failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, prg) {
+		t.Fatalf("Expected %q, got %q", prg.String(), decoded.String())
+	}
+}
+
+func TestJSONAssign(t *testing.T) {
+	a := &IdentifierEx{Token: token.Token{Typ: token.IDENT, Literal: "a"}, Value: "a"}
+	one := &IntegerLiteralEx{Token: token.Token{Typ: token.INT, Literal: "1"}, Value: 1}
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	prg := &Program{
+		StNodes: []Node{
+			&ExpressionSt{
+				RootToken: a.Token,
+				Expr: &AssignExpr{
+					Token: token.Token{Typ: token.PLUS_ASSIGN, Literal: "+="},
+					Target: &IndexExpr{
+						Token: token.Token{Typ: token.LBRACKET, Literal: "["},
+						Left:  a,
+						Index: one,
+					},
+					Op: "+=",
+					Value: &IntegerLiteralEx{
+						Token: token.Token{Typ: token.INT, Literal: huge.String()},
+						Big:   huge,
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(prg)
+	if err != nil {
+		t.Fatalf("Marshalling failed: %v", err)
+	}
+
+	decoded, err := UnmarshalProgram(data)
+	if err != nil {
+		t.Fatalf("Unmarshalling failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, prg) {
+		t.Fatalf("Expected %q, got %q", prg.String(), decoded.String())
+	}
+}
@@ -0,0 +1,65 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+)
+
+// every construct of the language, each node kind is in there
+const allConstructs = `let a: {string: [int]} = 1;
+let f: fn(int, float) -> bool = true;
+let big = 123456789012345678901234567890;
+let x = -a[0] + 1.5 * 0xFF ** 2;
+a[1] += !false && x || ~a;
+while (a < 10) {
+	a = a + 1;
+	break;
+}
+for (i in a) {
+	continue;
+}
+return a;
+`
+
+func TestJSONRoundTripParsed(t *testing.T) {
+	p := parser.New(lexer.New(allConstructs))
+	prg := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser got errors: %v", p.Errors())
+	}
+
+	data, err := json.Marshal(prg)
+	if err != nil {
+		t.Fatalf("Marshalling failed: %v", err)
+	}
+
+	kinds := []string{
+		"Program", "LetSt", "ReturnSt", "ExpressionSt", "WhileSt", "ForSt", "BlockSt", "BreakSt", "ContinueSt",
+		"IdentifierEx", "IntegerLiteralEx", "FloatLiteral", "BooleanLiteral",
+		"PrefixExpr", "InfixExpr", "IndexExpr", "AssignExpr", "TypeExpr",
+	}
+	for _, kind := range kinds {
+		if !strings.Contains(string(data), `"kind":"`+kind+`"`) {
+			t.Fatalf("Expected kind %s in %s", kind, data)
+		}
+	}
+
+	decoded, err := ast.UnmarshalProgram(data)
+	if err != nil {
+		t.Fatalf("Unmarshalling failed: %v", err)
+	}
+
+	if decoded.String() != prg.String() {
+		t.Fatalf("Expected %q, got %q", prg.String(), decoded.String())
+	}
+
+	if !reflect.DeepEqual(decoded, prg) {
+		t.Fatalf("Decoded program differs from the parsed one")
+	}
+}
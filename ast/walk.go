@@ -0,0 +1,47 @@
+package ast
+
+// Inspect traverses the tree depth-first in source order, calling f for
+// every node. Children of a node are skipped if f returns false for it.
+// Missing children, e.g. left by a failed parse, are not visited
+func Inspect(n Node, f func(Node) bool) {
+	if isNilNode(n) || !f(n) {
+		return
+	}
+
+	switch n := n.(type) {
+	case *Program:
+		for _, st := range n.StNodes {
+			Inspect(st, f)
+		}
+	case *LetSt:
+		Inspect(n.Ident, f)
+		Inspect(n.Type, f)
+		Inspect(n.Expr, f)
+	case *ReturnSt:
+		Inspect(n.Expr, f)
+	case *ExpressionSt:
+		Inspect(n.Expr, f)
+	case *PrefixExpr:
+		Inspect(n.Right, f)
+	case *InfixExpr:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *IndexExpr:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *AssignExpr:
+		Inspect(n.Target, f)
+		Inspect(n.Value, f)
+	case *BlockSt:
+		for _, st := range n.StNodes {
+			Inspect(st, f)
+		}
+	case *WhileSt:
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+	case *ForSt:
+		Inspect(n.Ident, f)
+		Inspect(n.Collection, f)
+		Inspect(n.Body, f)
+	}
+}
@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+func TestInspect(t *testing.T) {
+	ident := func(name string) *IdentifierEx {
+		return &IdentifierEx{Token: token.Token{Typ: token.IDENT, Literal: name}, Value: name}
+	}
+
+	// let a = -b + c; return a;
+	prg := &Program{
+		StNodes: []Node{
+			&LetSt{
+				Ident: ident("a"),
+				Expr: &InfixExpr{
+					Left:  &PrefixExpr{Op: "-", Right: ident("b")},
+					Op:    "+",
+					Right: ident("c"),
+				},
+			},
+			&ReturnSt{Expr: ident("a")},
+		},
+	}
+
+	var visited []string
+	Inspect(prg, func(n Node) bool {
+		name := fmt.Sprintf("%T", n)
+		if id, ok := n.(*IdentifierEx); ok {
+			name += " " + id.Value
+		}
+		visited = append(visited, name)
+
+		// don't go into prefix expressions
+		_, isPrefix := n.(*PrefixExpr)
+		return !isPrefix
+	})
+
+	const expected = "*ast.Program, *ast.LetSt, *ast.IdentifierEx a, *ast.InfixExpr, *ast.PrefixExpr, " +
+		"*ast.IdentifierEx c, *ast.ReturnSt, *ast.IdentifierEx a"
+
+	if got := strings.Join(visited, ", "); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+}
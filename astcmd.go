@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/diag"
+)
+
+// runAST is *monkey ast [--json|--dot] [--no-color] [file]*. Prints the AST of the file or stdin.
+// With --json the parse errors are printed as a JSON array instead of the AST
+func runAST(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+	asDOT := flags.Bool("dot", false, "print the AST as a Graphviz graph")
+	noColor := flags.Bool("no-color", false, "print errors without colors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *asJSON && *asDOT {
+		fmt.Fprintln(os.Stderr, "ast: --json and --dot can't be used together")
+		return 2
+	}
+
+	if flags.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "ast: expected at most one file")
+		return 2
+	}
+
+	prg, err := parseFile(flags.Arg(0))
+	if err != nil {
+		name := flags.Arg(0)
+		if name == "" {
+			name = "<stdin>"
+		}
+
+		ds, ok := errorDiagnostics(name, err)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "ast: %v\n", err)
+			return 1
+		}
+		if *asJSON {
+			if err := diag.WriteJSON(os.Stdout, ds); err != nil {
+				fmt.Fprintf(os.Stderr, "ast: %v\n", err)
+			}
+			return 1
+		}
+		printStreamed(os.Stderr, flags.Arg(0), ds, *noColor)
+		return 1
+	}
+
+	if *asJSON {
+		return printJSON(prg)
+	}
+
+	if *asDOT {
+		fmt.Print(ast.ToDOT(prg))
+		return 0
+	}
+
+	fmt.Print(prg.String())
+
+	return 0
+}
+
+func printJSON(prg *ast.Program) int {
+	data, err := json.MarshalIndent(prg, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ast: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(string(data))
+
+	return 0
+}
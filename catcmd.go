@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grzkv/m-interpreter/highlight"
+)
+
+// runCat is *monkey cat [--html] [--no-color] [files...]*. Prints the files
+// or stdin with syntax highlighting for the terminal or as HTML
+func runCat(args []string) int {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	asHTML := flags.Bool("html", false, "print HTML with CSS classes instead of terminal colors")
+	noColor := flags.Bool("no-color", false, "print the code as it is instead of coloring it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	color := useColor(os.Stdout, *noColor)
+
+	code := 0
+	for _, name := range names {
+		src, err := readSource(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cat: %v\n", err)
+			code = 1
+			continue
+		}
+
+		printSource(os.Stdout, src, *asHTML, color)
+	}
+
+	return code
+}
+
+// printSource writes the code as HTML, with terminal colors or as it is
+func printSource(w io.Writer, src string, asHTML, color bool) {
+	switch {
+	case asHTML:
+		fmt.Fprint(w, highlight.HTML(src))
+	case color:
+		fmt.Fprint(w, highlight.ANSI(src))
+	default:
+		fmt.Fprint(w, src)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grzkv/m-interpreter/diag"
+	"github.com/grzkv/m-interpreter/types"
+)
+
+// runCheck is *monkey check [--json] [--no-color] [files...]*. Without
+// files checks stdin. Exit code is 1 if there are type errors
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the errors as a JSON array")
+	noColor := flags.Bool("no-color", false, "print without colors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	code := 0
+	all := []diag.Diagnostic{}
+	for _, path := range names {
+		prg, err := parseFile(path)
+
+		name := path
+		if name == "" {
+			name = "<stdin>"
+		}
+
+		var ds []diag.Diagnostic
+		if err != nil {
+			var ok bool
+			if ds, ok = errorDiagnostics(name, err); !ok {
+				fmt.Fprintf(os.Stderr, "check: %v\n", err)
+				code = 1
+				continue
+			}
+		} else {
+			_, errs := types.Check(prg)
+			ds = typeDiagnostics(name, errs)
+		}
+
+		if len(ds) != 0 {
+			code = 1
+		}
+
+		switch {
+		case *asJSON:
+			all = append(all, ds...)
+		case err != nil:
+			printStreamed(os.Stderr, path, ds, *noColor)
+		default:
+			printStreamed(os.Stdout, path, ds, *noColor)
+		}
+	}
+
+	if *asJSON {
+		if err := diag.WriteJSON(os.Stdout, all); err != nil {
+			fmt.Fprintf(os.Stderr, "check: %v\n", err)
+			return 1
+		}
+	}
+
+	return code
+}
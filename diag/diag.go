@@ -0,0 +1,285 @@
+// Package diag prints diagnostics in the style of rustc: a header with the
+// severity and the message, the location, the source lines with the spans
+// underlined, and notes:
+//
+//	error: expected IDENT, got =
+//	 --> prog.mk:2:9
+//	  |
+//	2 | let x = = 1;
+//	  | ---     ^
+//	  | |
+//	  | let statement started here
+//	  |
+//	  = help: ...
+//
+// Diagnostics are also printed as JSON for tools.
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Severity tells how bad the diagnostic is
+type Severity string
+
+// severities
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Label is a secondary span with an explanation, e.g. where the
+// statement with the error started
+type Label struct {
+	Pos token.Pos `json:"pos"`
+	Msg string    `json:"message"`
+}
+
+// Diagnostic is an error or a warning about a place in the code
+type Diagnostic struct {
+	Severity Severity  `json:"severity"`
+	File     string    `json:"file"`
+	Pos      token.Pos `json:"pos"`
+	Msg      string    `json:"message"`
+	// Code identifies the kind of the problem, e.g. a vet rule
+	Code   string   `json:"code,omitempty"`
+	Labels []Label  `json:"labels,omitempty"`
+	Notes  []string `json:"notes,omitempty"`
+	Help   []string `json:"help,omitempty"`
+}
+
+// ANSI styles
+const (
+	bold   = "\x1b[1m"
+	red    = "\x1b[1;31m"
+	yellow = "\x1b[1;33m"
+	blue   = "\x1b[1;34m"
+	reset  = "\x1b[0m"
+)
+
+// Printer renders the diagnostics of one source file
+type Printer struct {
+	lines []string
+	// tokens by position, the spans are the tokens at the positions
+	tokens map[token.Pos]token.Token
+	color  bool
+}
+
+// NewPrinter makes a printer of the diagnostics of the code.
+// With color set the output has ANSI escapes
+func NewPrinter(src string, color bool) *Printer {
+	pr := &Printer{
+		lines:  strings.Split(strings.TrimPrefix(src, "\uFEFF"), "\n"),
+		tokens: make(map[token.Pos]token.Token),
+		color:  color,
+	}
+
+	tokens, _ := lexer.Tokenize(src)
+	for _, t := range tokens {
+		pr.tokens[t.Pos] = t
+	}
+
+	return pr
+}
+
+func (pr *Printer) style(s, text string) string {
+	if !pr.color {
+		return text
+	}
+
+	return s + text + reset
+}
+
+// Print writes the report of the diagnostic
+func (pr *Printer) Print(w io.Writer, d Diagnostic) error {
+	var b strings.Builder
+
+	sevStyle := red
+	if d.Severity == Warning {
+		sevStyle = yellow
+	}
+
+	header := string(d.Severity)
+	if d.Code != "" {
+		header += "[" + d.Code + "]"
+	}
+	b.WriteString(pr.style(sevStyle, header) + pr.style(bold, ": "+d.Msg) + "\n")
+
+	// the gutter fits the biggest line number
+	lines := []int{d.Pos.Line}
+	for _, l := range d.Labels {
+		lines = append(lines, l.Pos.Line)
+	}
+	sort.Ints(lines)
+	width := len(strconv.Itoa(lines[len(lines)-1]))
+	gutter := pr.style(blue, strings.Repeat(" ", width)+" |")
+
+	b.WriteString(strings.Repeat(" ", width) + pr.style(blue, "--> ") + d.File + ":" + d.Pos.String() + "\n")
+	b.WriteString(gutter + "\n")
+
+	prev := 0
+	for _, line := range lines {
+		if line == prev {
+			continue
+		}
+		if prev != 0 && line > prev+1 {
+			b.WriteString(pr.style(blue, "...") + "\n")
+		}
+		prev = line
+
+		pr.snippet(&b, line, width, sevStyle, d)
+	}
+
+	if len(d.Notes) != 0 || len(d.Help) != 0 {
+		b.WriteString(gutter + "\n")
+	}
+	for _, n := range d.Notes {
+		b.WriteString(strings.Repeat(" ", width) + pr.style(blue, " = ") + pr.style(bold, "note") + ": " + n + "\n")
+	}
+	for _, h := range d.Help {
+		b.WriteString(strings.Repeat(" ", width) + pr.style(blue, " = ") + pr.style(bold, "help") + ": " + h + "\n")
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// span is an underlined part of a line
+type span struct {
+	col, len int
+	primary  bool
+	msg      string
+}
+
+// snippet writes the source line with the spans of the diagnostic on it
+// underlined: ^ for the primary span and - for the labels, whose messages
+// go on the lines below
+func (pr *Printer) snippet(b *strings.Builder, line, width int, sevStyle string, d Diagnostic) {
+	num := strconv.Itoa(line)
+	b.WriteString(pr.style(blue, strings.Repeat(" ", width-len(num))+num+" |"))
+
+	text := ""
+	if line-1 < len(pr.lines) {
+		text = strings.TrimRight(pr.lines[line-1], "\r")
+	}
+	if text != "" {
+		b.WriteString(" " + text)
+	}
+	b.WriteString("\n")
+
+	var spans []span
+	if d.Pos.Line == line {
+		spans = append(spans, span{col: d.Pos.Col, len: pr.spanLen(d.Pos), primary: true})
+	}
+	for _, l := range d.Labels {
+		if l.Pos.Line == line {
+			spans = append(spans, span{col: l.Pos.Col, len: pr.spanLen(l.Pos), msg: l.Msg})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].col < spans[j].col })
+
+	gutter := pr.style(blue, strings.Repeat(" ", width)+" |")
+	blank := blanker(text)
+
+	// the underlines
+	var marks strings.Builder
+	col := 1
+	for _, s := range spans {
+		if s.col < col {
+			continue
+		}
+		marks.WriteString(blank(col, s.col))
+		if s.primary {
+			marks.WriteString(pr.style(sevStyle, strings.Repeat("^", s.len)))
+		} else {
+			marks.WriteString(pr.style(blue, strings.Repeat("-", s.len)))
+		}
+		col = s.col + s.len
+	}
+
+	// the last label message fits on the underline line, the others
+	// hang below their spans
+	var hanging []span
+	for _, s := range spans {
+		if s.msg != "" {
+			hanging = append(hanging, s)
+		}
+	}
+	if n := len(hanging); n != 0 && hanging[n-1].col+hanging[n-1].len >= col {
+		marks.WriteString(" " + pr.style(blue, hanging[n-1].msg))
+		hanging = hanging[:n-1]
+	}
+	b.WriteString(gutter + " " + marks.String() + "\n")
+
+	for i := len(hanging) - 1; i >= 0; i-- {
+		b.WriteString(gutter + " " + pr.bars(blank, hanging[:i+1], 0) + "\n")
+		b.WriteString(gutter + " " + pr.bars(blank, hanging[:i], hanging[i].col) + pr.style(blue, hanging[i].msg) + "\n")
+	}
+}
+
+// bars draws | under the starts of the spans and blanks up to the column
+func (pr *Printer) bars(blank func(from, to int) string, spans []span, to int) string {
+	var b strings.Builder
+	col := 1
+	for _, s := range spans {
+		b.WriteString(blank(col, s.col) + pr.style(blue, "|"))
+		col = s.col + 1
+	}
+	b.WriteString(blank(col, to))
+
+	return b.String()
+}
+
+// blanker returns the function making the blank space that lines up with
+// the columns of the text: tabs stay tabs, everything else becomes spaces
+func blanker(text string) func(from, to int) string {
+	runes := []rune(text)
+
+	return func(from, to int) string {
+		var b strings.Builder
+		for col := from; col < to; col++ {
+			if col-1 < len(runes) && runes[col-1] == '\t' {
+				b.WriteByte('\t')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+
+		return b.String()
+	}
+}
+
+// spanLen is the length in runes of the token at the position, at least 1
+func (pr *Printer) spanLen(pos token.Pos) int {
+	if t, ok := pr.tokens[pos]; ok {
+		if n := utf8.RuneCountInString(t.Literal); n > 0 {
+			return n
+		}
+	}
+
+	return 1
+}
+
+// WriteJSON writes the diagnostics as a JSON array
+func WriteJSON(w io.Writer, ds []Diagnostic) error {
+	if ds == nil {
+		ds = []Diagnostic{}
+	}
+
+	data, err := json.MarshalIndent(ds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
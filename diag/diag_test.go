@@ -0,0 +1,151 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+func render(t *testing.T, src string, d Diagnostic, color bool) string {
+	var b bytes.Buffer
+	if err := NewPrinter(src, color).Print(&b, d); err != nil {
+		t.Fatalf("Printing failed: %v", err)
+	}
+
+	return b.String()
+}
+
+func TestPrint(t *testing.T) {
+	src := "let a = 1;\nlet bcd = = 2;\n\n\nwhile (a) {\n\tlet x = a +;\n}"
+
+	tests := []struct {
+		d        Diagnostic
+		expected string
+	}{
+		{
+			Diagnostic{
+				Severity: Error,
+				File:     "prog.mk",
+				Pos:      token.Pos{Line: 2, Col: 11},
+				Msg:      "no prefix parse function for =",
+				Labels:   []Label{{Pos: token.Pos{Line: 2, Col: 1}, Msg: "let statement started here"}},
+			},
+			`error: no prefix parse function for =
+ --> prog.mk:2:11
+  |
+2 | let bcd = = 2;
+  | ---       ^
+  | |
+  | let statement started here
+
+`,
+		},
+		{
+			Diagnostic{
+				Severity: Warning,
+				File:     "prog.mk",
+				Pos:      token.Pos{Line: 2, Col: 5},
+				Msg:      "declaration of bcd shadows declaration",
+				Code:     "shadow",
+				Labels:   []Label{{Pos: token.Pos{Line: 2, Col: 11}, Msg: "the value"}},
+				Notes:    []string{"shadowing hides the outer name"},
+				Help:     []string{"rename one of them"},
+			},
+			`warning[shadow]: declaration of bcd shadows declaration
+ --> prog.mk:2:5
+  |
+2 | let bcd = = 2;
+  |     ^^^   - the value
+  |
+  = note: shadowing hides the outer name
+  = help: rename one of them
+
+`,
+		},
+		{
+			Diagnostic{
+				Severity: Error,
+				File:     "prog.mk",
+				Pos:      token.Pos{Line: 6, Col: 13},
+				Msg:      "no prefix parse function for ;",
+				Labels: []Label{
+					{Pos: token.Pos{Line: 6, Col: 2}, Msg: "let statement started here"},
+					{Pos: token.Pos{Line: 1, Col: 5}, Msg: "a is declared here"},
+				},
+			},
+			`error: no prefix parse function for ;
+ --> prog.mk:6:13
+  |
+1 | let a = 1;
+  |     - a is declared here
+...
+6 | 	let x = a +;
+  | 	---        ^
+  | 	|
+  | 	let statement started here
+
+`,
+		},
+	}
+
+	for _, tst := range tests {
+		if got := render(t, src, tst.d, false); got != tst.expected {
+			t.Fatalf("Expected\n%s\ngot\n%s", tst.expected, got)
+		}
+	}
+}
+
+func TestPrintColor(t *testing.T) {
+	d := Diagnostic{Severity: Error, File: "f.mk", Pos: token.Pos{Line: 1, Col: 9}, Msg: "bad"}
+
+	got := render(t, "let a = @;", d, true)
+	if !strings.Contains(got, "\x1b[1;31merror\x1b[0m") || !strings.Contains(got, "\x1b[1;31m^\x1b[0m") {
+		t.Fatalf("Expected colored output, got %q", got)
+	}
+
+	plain := render(t, "let a = @;", d, false)
+	if strings.Contains(plain, "\x1b") {
+		t.Fatalf("Expected no escapes, got %q", plain)
+	}
+}
+
+func TestPrintPastEnd(t *testing.T) {
+	d := Diagnostic{Severity: Error, File: "f.mk", Pos: token.Pos{Line: 2, Col: 1}, Msg: "expected }, got EOF"}
+
+	expected := "error: expected }, got EOF\n --> f.mk:2:1\n  |\n2 |\n  | ^\n\n"
+	if got := render(t, "while (a) {\n", d, false); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	ds := []Diagnostic{{
+		Severity: Warning,
+		File:     "f.mk",
+		Pos:      token.Pos{Line: 1, Col: 5},
+		Msg:      "a is declared but not used",
+		Code:     "unused",
+	}}
+
+	var b bytes.Buffer
+	if err := WriteJSON(&b, ds); err != nil {
+		t.Fatalf("Writing failed: %v", err)
+	}
+
+	var decoded []Diagnostic
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("Bad JSON %s: %v", b.String(), err)
+	}
+	if len(decoded) != 1 || decoded[0].Code != "unused" || decoded[0].Pos != ds[0].Pos {
+		t.Fatalf("Unexpected JSON %s", b.String())
+	}
+
+	b.Reset()
+	WriteJSON(&b, nil)
+	if b.String() != "[]\n" {
+		t.Fatalf("Expected empty array, got %q", b.String())
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grzkv/m-interpreter/diag"
+	"github.com/grzkv/m-interpreter/parser"
+	"github.com/grzkv/m-interpreter/types"
+	"github.com/grzkv/m-interpreter/vet"
+)
+
+// useColor tells if the diagnostics written to the file get colors:
+// the file is a terminal, NO_COLOR is not set and --no-color is not given
+func useColor(f *os.File, noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// errorDiagnostics converts the error of parsing or reading the file.
+// Errors without a position are not diagnostics, ok is false for them
+func errorDiagnostics(name string, err error) (ds []diag.Diagnostic, ok bool) {
+	errs, ok := err.(parser.ErrorList)
+	if !ok {
+		return nil, false
+	}
+
+	for _, e := range errs {
+		d := diag.Diagnostic{Severity: diag.Error, File: name, Pos: e.Pos, Msg: e.Msg}
+		for _, l := range e.Labels {
+			d.Labels = append(d.Labels, diag.Label{Pos: l.Pos, Msg: l.Msg})
+		}
+		ds = append(ds, d)
+	}
+
+	return ds, true
+}
+
+func typeDiagnostics(name string, errs []types.Error) []diag.Diagnostic {
+	ds := make([]diag.Diagnostic, len(errs))
+	for i, e := range errs {
+		ds[i] = diag.Diagnostic{Severity: diag.Error, File: name, Pos: e.Pos, Msg: e.Msg}
+	}
+
+	return ds
+}
+
+func vetDiagnostics(name string, findings []vet.Finding) []diag.Diagnostic {
+	ds := make([]diag.Diagnostic, len(findings))
+	for i, f := range findings {
+		ds[i] = diag.Diagnostic{Severity: diag.Warning, File: name, Pos: f.Pos, Msg: f.Msg, Code: f.Rule}
+	}
+
+	return ds
+}
+
+// reportError prints the error of the named file to stderr, as
+// diagnostics with the source lines if it has positions
+func reportError(cmd, name, src string, err error, noColor bool) {
+	ds, ok := errorDiagnostics(name, err)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: %s: %v\n", cmd, name, err)
+		return
+	}
+
+	printDiagnostics(os.Stderr, src, ds, useColor(os.Stderr, noColor))
+}
+
+// printStreamed prints the diagnostics of the file parsed by parseFile.
+// The code is read again for the source lines. If it can't be, the
+// diagnostics are printed one per line without them
+func printStreamed(w *os.File, name string, ds []diag.Diagnostic, noColor bool) {
+	if len(ds) == 0 {
+		return
+	}
+
+	src, ok := rereadSource(name)
+	if !ok {
+		for _, d := range ds {
+			fmt.Fprintf(w, "%s:%s: %s: %s\n", d.File, d.Pos, d.Severity, d.Msg)
+		}
+		return
+	}
+
+	printDiagnostics(w, src, ds, useColor(w, noColor))
+}
+
+func printDiagnostics(w io.Writer, src string, ds []diag.Diagnostic, color bool) {
+	pr := diag.NewPrinter(src, color)
+	for _, d := range ds {
+		pr.Print(w, d)
+	}
+}
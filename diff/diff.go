@@ -0,0 +1,192 @@
+// Package diff makes unified diffs of texts, line by line, as printed
+// by monkey fmt -d
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around the changes
+const context = 3
+
+// edit is one line of the diff
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+	// line numbers in a and b before the edit
+	ai, bi int
+}
+
+// Unified makes a unified diff of two texts with the name in the header.
+// Returns empty string if they are equal
+func Unified(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+
+		// a hunk grows while changes are close enough to share the context
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		end = min(end+context, len(edits))
+
+		writeHunk(&out, edits[start:end])
+
+		k = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit) {
+	aLen, bLen := 0, 0
+	for _, e := range edits {
+		if e.op != '+' {
+			aLen++
+		}
+		if e.op != '-' {
+			bLen++
+		}
+	}
+
+	// an empty range starts at the line before it
+	aStart, bStart := edits[0].ai+1, edits[0].bi+1
+	if aLen == 0 {
+		aStart--
+	}
+	if bLen == 0 {
+		bStart--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, e := range edits {
+		out.WriteString(string(e.op) + e.line + "\n")
+	}
+}
+
+// lineEdits finds a shortest edit script turning a into b with the
+// algorithm of E. Myers, "An O(ND) Difference Algorithm and Its
+// Variations". It takes O((N+M)D) time and O(D²) memory for D edits,
+// which is small for formatted code
+func lineEdits(a, b []string) []edit {
+	n, m := len(a), len(b)
+
+	// v[k] is the furthest x reached on diagonal k = x - y, offset by
+	// d in the copy kept for step d
+	v := make([]int, 2*(n+m)+3)
+	off := n + m + 1
+	var trace [][]int
+
+	d := 0
+search:
+	for ; ; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				// down: insertion
+				x = v[off+k+1]
+			} else {
+				// right: deletion
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+				break search
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+
+	// back from the end to the start, the edits come in reverse
+	var rev []edit
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, edit{' ', a[x], x, y})
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, edit{'+', b[y], x, y})
+		} else {
+			x--
+			rev = append(rev, edit{'-', a[x], x, y})
+		}
+	}
+	for x > 0 {
+		x--
+		y--
+		rev = append(rev, edit{' ', a[x], x, y})
+	}
+
+	edits := make([]edit, len(rev))
+	for i, e := range rev {
+		edits[len(rev)-1-i] = e
+	}
+
+	return edits
+}
+
+// noNewline marks the last line of a text that does not end with a newline.
+// The line differs from the same line with a newline, and the marker is
+// printed after it as diff does
+const noNewline = "\n\\ No newline at end of file"
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += noNewline
+	}
+
+	return lines
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lines makes text of the numbered lines from..to, with the replacements
+func lines(from, to int, repl map[int]string) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		if r, ok := repl[i]; ok {
+			if r != "" {
+				b.WriteString(r + "\n")
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+
+	return b.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"empty", "", "", ""},
+		{
+			"first line",
+			"x\nb\nc\nd\ne\nf\n", "a\nb\nc\nd\ne\nf\n",
+			"@@ -1,4 +1,4 @@\n-x\n+a\n b\n c\n d\n",
+		},
+		{
+			"last line",
+			"a\nb\nc\nd\ne\nx\n", "a\nb\nc\nd\ne\nf\n",
+			"@@ -3,4 +3,4 @@\n c\n d\n e\n-x\n+f\n",
+		},
+		{
+			"context trimmed",
+			lines(1, 20, nil), lines(1, 20, map[int]string{10: "ten"}),
+			"@@ -7,7 +7,7 @@\n line 7\n line 8\n line 9\n-line 10\n+ten\n line 11\n line 12\n line 13\n",
+		},
+		{
+			"hunks merged",
+			lines(1, 20, nil), lines(1, 20, map[int]string{5: "five", 11: "eleven"}),
+			"@@ -2,13 +2,13 @@\n line 2\n line 3\n line 4\n-line 5\n+five\n line 6\n line 7\n line 8\n line 9\n line 10\n-line 11\n+eleven\n line 12\n line 13\n line 14\n",
+		},
+		{
+			"hunks apart",
+			lines(1, 20, nil), lines(1, 20, map[int]string{5: "five", 13: "thirteen"}),
+			"@@ -2,7 +2,7 @@\n line 2\n line 3\n line 4\n-line 5\n+five\n line 6\n line 7\n line 8\n" +
+				"@@ -10,7 +10,7 @@\n line 10\n line 11\n line 12\n-line 13\n+thirteen\n line 14\n line 15\n line 16\n",
+		},
+		{
+			"insertion and deletion",
+			"a\nb\nc\n", "a\nc\nd\n",
+			"@@ -1,3 +1,3 @@\n a\n-b\n c\n+d\n",
+		},
+		{"from empty", "", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\n", "", "@@ -1,1 +0,0 @@\n-a\n"},
+		{"missing newline", "a\nb", "a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+	}
+
+	for _, tst := range tests {
+		got := Unified("f.mk", tst.a, tst.b)
+
+		expected := tst.expected
+		if expected != "" {
+			expected = "--- f.mk\n+++ f.mk\n" + expected
+		}
+
+		if got != expected {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", tst.name, expected, got)
+		}
+	}
+}
+
+// the edits have to turn a into b whatever the input
+func TestLineEdits(t *testing.T) {
+	texts := []string{"", "a", "a\nb\nc", "c\nb\na", "a\na\nb\na", "x\ny\nz\na\nb", "b\na\nb\na\nc"}
+
+	for _, a := range texts {
+		for _, b := range texts {
+			al, bl := splitLines(a), splitLines(b)
+
+			var fromA, toB []string
+			for _, e := range lineEdits(al, bl) {
+				if e.op != '+' {
+					fromA = append(fromA, e.line)
+				}
+				if e.op != '-' {
+					toB = append(toB, e.line)
+				}
+			}
+
+			if strings.Join(fromA, "\n") != strings.Join(al, "\n") || strings.Join(toB, "\n") != strings.Join(bl, "\n") {
+				t.Fatalf("Edits of %q to %q are wrong: %+v", a, b, lineEdits(al, bl))
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grzkv/m-interpreter/diag"
+	"github.com/grzkv/m-interpreter/diff"
+	"github.com/grzkv/m-interpreter/format"
+)
+
+// runFmt is *monkey fmt [-w] [-d] [--json] [--no-color] files...*. Without files formats stdin.
+// With --json the parse errors of all files are printed to stderr as one JSON array
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	asDiff := flags.Bool("d", false, "print diffs instead of the formatted code")
+	asJSON := flags.Bool("json", false, "print the parse errors as a JSON array")
+	noColor := flags.Bool("no-color", false, "print errors without colors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// parse errors of all files for --json, nil prints them as they come
+	var all *[]diag.Diagnostic
+	if *asJSON {
+		all = &[]diag.Diagnostic{}
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "fmt: can't use -w with stdin")
+			return 2
+		}
+		code := fmtFile("<stdin>", os.Stdin, false, *asDiff, *noColor, all)
+		return writeJSON(all, code)
+	}
+
+	code := 0
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+			code = 1
+			continue
+		}
+
+		if c := fmtFile(name, f, *write, *asDiff, *noColor, all); c != 0 {
+			code = c
+		}
+		f.Close()
+	}
+
+	return writeJSON(all, code)
+}
+
+// writeJSON prints the collected parse errors for --json, if any were
+// collected. The code to exit with is returned back
+func writeJSON(all *[]diag.Diagnostic, code int) int {
+	if all == nil {
+		return code
+	}
+
+	if err := diag.WriteJSON(os.Stderr, *all); err != nil {
+		fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+		return 1
+	}
+
+	return code
+}
+
+func fmtFile(name string, f *os.File, write, asDiff, noColor bool, all *[]diag.Diagnostic) int {
+	src, err := io.ReadAll(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fmt: %s: %v\n", name, err)
+		return 1
+	}
+
+	res, err := format.Source(string(src))
+	if err != nil {
+		if all == nil {
+			reportError("fmt", name, string(src), err, noColor)
+			return 1
+		}
+
+		ds, ok := errorDiagnostics(name, err)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "fmt: %s: %v\n", name, err)
+			return 1
+		}
+		*all = append(*all, ds...)
+		return 1
+	}
+
+	if asDiff {
+		fmt.Print(diff.Unified(name, string(src), res))
+	}
+
+	if write {
+		if res == string(src) {
+			return 0
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(name, []byte(res), fi.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %v\n", err)
+			return 1
+		}
+	} else if !asDiff {
+		fmt.Print(res)
+	}
+
+	return 0
+}
@@ -0,0 +1,265 @@
+// Package format prints Monkey code in the canonical style: one statement
+// per line, every statement but loops terminated by a semicolon, blocks
+// indented with tabs, single spaces around infix and assignment operators
+// and only the parentheses that the operator precedence requires. Comments
+// are kept, runs of blank lines are collapsed into one.
+package format
+
+import (
+	"strings"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Source formats the code. The code has to parse without errors.
+// Formatting is idempotent: Source of the result gives the result back
+func Source(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	prg := p.Parse()
+
+	if errs := p.ErrorList(); len(errs) != 0 {
+		return "", errs
+	}
+
+	pr := printer{
+		comments: collectComments(src),
+		lines:    strings.Split(src, "\n"),
+	}
+	pr.program(prg)
+
+	return pr.b.String(), nil
+}
+
+// Node formats a single node. There are no comments in the AST,
+// so none are printed
+func Node(n ast.Node) string {
+	var pr printer
+
+	switch n := n.(type) {
+	case *ast.Program:
+		pr.program(n)
+	case ast.ExprNode:
+		pr.expr(n, parser.LOWEST)
+	default:
+		pr.statement(n)
+	}
+
+	return pr.b.String()
+}
+
+type comment struct {
+	tok token.Token
+	// there is code before the comment on its line
+	trailing bool
+}
+
+func collectComments(src string) []comment {
+	var comments []comment
+
+	prevLine := 0
+	for t := range lexer.NewWithComments(src).All() {
+		if t.Typ != token.COMMENT {
+			prevLine = t.Pos.Line
+			continue
+		}
+		comments = append(comments, comment{tok: t, trailing: t.Pos.Line == prevLine})
+	}
+
+	return comments
+}
+
+type printer struct {
+	b strings.Builder
+
+	comments []comment
+	// source lines, used to keep blank lines
+	lines []string
+	// something was printed already in the current statement list
+	started bool
+	indent  int
+}
+
+func (pr *printer) program(prg *ast.Program) {
+	pr.statements(prg.StNodes, -1)
+}
+
+// statements prints a statement list with the comments among the statements.
+// The list ends before the given line, -1 means the end of the code
+func (pr *printer) statements(sts []ast.Node, end int) {
+	for i, st := range sts {
+		line := ast.Pos(st).Line
+		pr.commentsBefore(line)
+
+		pr.blankLineBefore(line)
+		pr.writeIndent()
+		pr.statement(st)
+
+		nextLine := end
+		if i+1 < len(sts) {
+			nextLine = ast.Pos(sts[i+1]).Line
+		}
+		pr.trailingComment(nextLine)
+
+		pr.b.WriteString("\n")
+	}
+
+	pr.commentsBefore(end)
+}
+
+// commentsBefore prints the comments which are on the lines before the
+// given one, each on a line of its own. -1 means all comments left
+func (pr *printer) commentsBefore(line int) {
+	for len(pr.comments) != 0 && (line == -1 || pr.comments[0].tok.Pos.Line < line) {
+		c := pr.comments[0]
+		pr.comments = pr.comments[1:]
+
+		pr.blankLineBefore(c.tok.Pos.Line)
+		pr.writeIndent()
+		pr.b.WriteString(c.tok.Literal + "\n")
+	}
+}
+
+func (pr *printer) writeIndent() {
+	pr.b.WriteString(strings.Repeat("\t", pr.indent))
+}
+
+// trailingComment appends the comment that follows the statement on the
+// same line. A statement can have only one, the others go on lines of their own
+func (pr *printer) trailingComment(nextLine int) {
+	if len(pr.comments) == 0 {
+		return
+	}
+
+	c := pr.comments[0]
+	if c.trailing && (nextLine == -1 || c.tok.Pos.Line < nextLine) {
+		pr.comments = pr.comments[1:]
+		pr.b.WriteString(" " + c.tok.Literal)
+	}
+}
+
+// blankLineBefore keeps one blank line if there was at least one in the
+// source before the given line. Nothing is printed before the first item
+func (pr *printer) blankLineBefore(line int) {
+	if pr.started && line >= 2 && line-2 < len(pr.lines) &&
+		strings.TrimSpace(pr.lines[line-2]) == "" {
+		pr.b.WriteString("\n")
+	}
+	pr.started = true
+}
+
+func (pr *printer) statement(st ast.Node) {
+	switch st := st.(type) {
+	case *ast.LetSt:
+		pr.b.WriteString("let " + st.Ident.Value)
+		if st.Type != nil {
+			pr.b.WriteString(": " + st.Type.String())
+		}
+		pr.b.WriteString(" = ")
+		pr.expr(st.Expr, parser.LOWEST)
+		pr.b.WriteString(";")
+	case *ast.ReturnSt:
+		pr.b.WriteString("return ")
+		pr.expr(st.Expr, parser.LOWEST)
+		pr.b.WriteString(";")
+	case *ast.ExpressionSt:
+		pr.expr(st.Expr, parser.LOWEST)
+		pr.b.WriteString(";")
+	case *ast.WhileSt:
+		pr.b.WriteString("while (")
+		pr.expr(st.Cond, parser.LOWEST)
+		pr.b.WriteString(") ")
+		pr.block(st.Body)
+	case *ast.ForSt:
+		pr.b.WriteString("for (" + st.Ident.Value + " in ")
+		pr.expr(st.Collection, parser.LOWEST)
+		pr.b.WriteString(") ")
+		pr.block(st.Body)
+	case *ast.BlockSt:
+		pr.block(st)
+	case *ast.BreakSt:
+		pr.b.WriteString("break;")
+	case *ast.ContinueSt:
+		pr.b.WriteString("continue;")
+	}
+}
+
+// block prints the statements in braces, indented one level deeper
+func (pr *printer) block(b *ast.BlockSt) {
+	end := b.Rbrace.Pos.Line
+	if len(b.StNodes) == 0 && (len(pr.comments) == 0 || pr.comments[0].tok.Pos.Line >= end) {
+		pr.b.WriteString("{}")
+		return
+	}
+
+	pr.b.WriteString("{\n")
+
+	pr.indent++
+	pr.started = false
+	pr.statements(b.StNodes, end)
+	pr.indent--
+
+	pr.writeIndent()
+	pr.b.WriteString("}")
+}
+
+// expr prints an expression which is an operand of an operator with the
+// given precedence. Parentheses are added if the expression binds weaker
+func (pr *printer) expr(e ast.ExprNode, prec int) {
+	own := exprPrecedence(e)
+	if own < prec {
+		pr.b.WriteString("(")
+		defer pr.b.WriteString(")")
+	}
+
+	switch e := e.(type) {
+	case *ast.IdentifierEx:
+		pr.b.WriteString(e.Value)
+	case *ast.IntegerLiteralEx:
+		pr.b.WriteString(e.Token.Literal)
+	case *ast.FloatLiteral:
+		pr.b.WriteString(e.Token.Literal)
+	case *ast.BooleanLiteral:
+		pr.b.WriteString(e.Token.Literal)
+	case *ast.PrefixExpr:
+		pr.b.WriteString(e.Op)
+		pr.expr(e.Right, parser.PREFIX)
+	case *ast.InfixExpr:
+		// operators but ** are left associative, so the operand on
+		// the other side of the same precedence needs parentheses
+		left, right := own, own+1
+		if e.OpToken.Typ == token.POWER {
+			left, right = own+1, own
+		}
+		pr.expr(e.Left, left)
+		pr.b.WriteString(" " + e.Op + " ")
+		pr.expr(e.Right, right)
+	case *ast.IndexExpr:
+		pr.expr(e.Left, own)
+		pr.b.WriteString("[")
+		pr.expr(e.Index, parser.LOWEST)
+		pr.b.WriteString("]")
+	case *ast.AssignExpr:
+		// right associative
+		pr.expr(e.Target, own+1)
+		pr.b.WriteString(" " + e.Op + " ")
+		pr.expr(e.Value, own)
+	}
+}
+
+func exprPrecedence(e ast.ExprNode) int {
+	switch e := e.(type) {
+	case *ast.PrefixExpr:
+		return parser.PREFIX
+	case *ast.InfixExpr:
+		return parser.Precedence(e.OpToken.Typ)
+	case *ast.IndexExpr:
+		return parser.INDEX
+	case *ast.AssignExpr:
+		return parser.ASSIGN
+	default:
+		return parser.INDEX + 1
+	}
+}
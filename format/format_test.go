@@ -0,0 +1,113 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+)
+
+var formatTests = []struct {
+	in       string
+	expected string
+}{
+	{"let x=1", "let x = 1;\n"},
+	{"return   a+b", "return a + b;\n"},
+	{"a;b;", "a;\nb;\n"},
+	{"(a + b) * c;", "(a + b) * c;\n"},
+	{"((a * b)) + c;", "a * b + c;\n"},
+	{"a - (b - c);", "a - (b - c);\n"},
+	{"(a - b) - c;", "a - b - c;\n"},
+	{"-(a + b);", "-(a + b);\n"},
+	{"(-a) * b;", "-a * b;\n"},
+	{"!(a == b);", "!(a == b);\n"},
+	{"a == (b < c);", "a == b < c;\n"},
+	{"(a == b) == c;", "a == b == c;\n"},
+	{"a == (b == c);", "a == (b == c);\n"},
+	{
+		"// header\n\n\n\nlet a = 1; // one\n// two\nlet b = 2;\n\n\nb;\n// tail\n",
+		"// header\n\nlet a = 1; // one\n// two\nlet b = 2;\n\nb;\n// tail\n",
+	},
+	{"let a = 1; let b = 2; // both\n", "let a = 1;\nlet b = 2; // both\n"},
+	{"let a = 1 +\n  2; // end\nlet b = 3;", "let a = 1 + 2; // end\nlet b = 3;\n"},
+	{"// only a comment", "// only a comment\n"},
+	{"let x:{string:[int]}=y", "let x: {string: [int]} = y;\n"},
+	{"while(a<b){a;}", "while (a < b) {\n\ta;\n}\n"},
+	{"while (a) {};", "while (a) {}\n"},
+	{
+		"for (x in xs) {\n  // first\n  while (x) { break }\n\n\n  continue;\n  // last\n} // done\nx;",
+		"for (x in xs) {\n\t// first\n\twhile (x) {\n\t\tbreak;\n\t}\n\n\tcontinue;\n\t// last\n} // done\nx;\n",
+	},
+	{"while (a) {\n  // nothing\n}", "while (a) {\n\t// nothing\n}\n"},
+	{"x=y=a+b", "x = y = a + b;\n"},
+	{"a[i+1]+=(-b)[0]", "a[i + 1] += (-b)[0];\n"},
+	{"-a[0] * (a[1])[2];", "-a[0] * a[1][2];\n"},
+	{"x *= (y = 2);", "x *= y = 2;\n"},
+	{"(a || b) && c || (d && e);", "(a || b) && c || d && e;\n"},
+	{"a || (b || c);", "a || (b || c);\n"},
+	{"-(a ** b);", "-a ** b;\n"},
+	{"(-a) ** b ** (c ** d);", "(-a) ** b ** c ** d;\n"},
+	{"(a ** b) ** c;", "(a ** b) ** c;\n"},
+	{"(a|b)&~c<<(d>>e)", "(a | b) & ~c << (d >> e);\n"},
+	{"a%b<=c", "a % b <= c;\n"},
+	{"let x:float=1.50e+00*2", "let x: float = 1.50e+00 * 2;\n"},
+	{"-(1.5)", "-1.5;\n"},
+	{"0xFF+0o17*0b1010", "0xFF + 0o17 * 0b1010;\n"},
+	{"let big=1_000_000;", "let big = 1_000_000;\n"},
+	{"\uFEFFlet café=été2", "let café = été2;\n"},
+	{"x=true&&!(false)", "x = true && !false;\n"},
+	{"", ""},
+}
+
+func TestSource(t *testing.T) {
+	for _, tst := range formatTests {
+		got, err := Source(tst.in)
+		if err != nil {
+			t.Fatalf("Formatting %q failed: %v", tst.in, err)
+		}
+
+		if got != tst.expected {
+			t.Fatalf("Formatting %q: expected %q, got %q", tst.in, tst.expected, got)
+		}
+	}
+}
+
+func TestSourceIdempotent(t *testing.T) {
+	for _, tst := range formatTests {
+		once, err := Source(tst.in)
+		if err != nil {
+			t.Fatalf("Formatting %q failed: %v", tst.in, err)
+		}
+
+		twice, err := Source(once)
+		if err != nil {
+			t.Fatalf("Formatting %q failed: %v", once, err)
+		}
+
+		if once != twice {
+			t.Fatalf("Formatting is not idempotent: %q became %q", once, twice)
+		}
+	}
+}
+
+func TestSourceKeepsAST(t *testing.T) {
+	for _, tst := range formatTests {
+		formatted, err := Source(tst.in)
+		if err != nil {
+			t.Fatalf("Formatting %q failed: %v", tst.in, err)
+		}
+
+		orig := parser.New(lexer.New(tst.in)).Parse().String()
+		reparsed := parser.New(lexer.New(formatted)).Parse().String()
+
+		if orig != reparsed {
+			t.Fatalf("AST changed after formatting %q: %q became %q", tst.in, orig, reparsed)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	if _, err := Source("let = 1;"); err == nil {
+		t.Fatal("Expected an error for code that does not parse")
+	}
+}
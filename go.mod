@@ -0,0 +1,3 @@
+module github.com/grzkv/m-interpreter
+
+go 1.23
@@ -0,0 +1,154 @@
+// Package highlight renders Monkey code with syntax highlighting, with ANSI
+// escapes for terminals or as HTML with CSS classes. The code is printed as
+// it is, only the tokens are decorated, so the code does not have to parse
+package highlight
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Class is the kind of a token for highlighting
+type Class int
+
+// token classes
+const (
+	// Plain is for delimiters and parentheses, they are not decorated
+	Plain Class = iota
+	Keyword
+	Literal
+	Operator
+	Ident
+	Comment
+	Illegal
+)
+
+var classNames = [...]string{
+	Plain:    "plain",
+	Keyword:  "keyword",
+	Literal:  "literal",
+	Operator: "operator",
+	Ident:    "ident",
+	Comment:  "comment",
+	Illegal:  "illegal",
+}
+
+// String is the CSS class name of the class
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// ClassOf returns the class of the token type
+func ClassOf(typ token.Typ) Class {
+	switch {
+	case typ == token.COMMENT:
+		return Comment
+	case typ == token.ILLEGAL:
+		return Illegal
+	case typ == token.IDENT:
+		return Ident
+	case typ.IsKeyword():
+		return Keyword
+	case typ.IsLiteral():
+		return Literal
+	case typ.IsOperator():
+		return Operator
+	}
+
+	return Plain
+}
+
+// ansiStyles are the escape sequences of the classes. Identifiers are
+// left in the terminal color
+var ansiStyles = [...]string{
+	Keyword:  "\x1b[1;35m",
+	Literal:  "\x1b[36m",
+	Operator: "\x1b[33m",
+	Comment:  "\x1b[90m",
+	Illegal:  "\x1b[4;31m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// ANSI highlights the code with terminal escape sequences
+func ANSI(src string) string {
+	var b strings.Builder
+
+	render(src, func(text string, c Class) {
+		if style := ansiStyles[c]; style != "" {
+			b.WriteString(style + text + ansiReset)
+		} else {
+			b.WriteString(text)
+		}
+	})
+
+	return b.String()
+}
+
+// HTML highlights the code as a <pre class="monkey"> element. Every token
+// but the plain ones is a <span> with the class named after its Class,
+// e.g. <span class="keyword">let</span>
+func HTML(src string) string {
+	var b strings.Builder
+
+	b.WriteString(`<pre class="monkey">`)
+	render(src, func(text string, c Class) {
+		if c == Plain {
+			b.WriteString(html.EscapeString(text))
+		} else {
+			b.WriteString(`<span class="` + c.String() + `">` + html.EscapeString(text) + `</span>`)
+		}
+	})
+	b.WriteString("</pre>\n")
+
+	return b.String()
+}
+
+// render calls emit for the pieces of the code in order: tokens with
+// their class and the text between them as Plain
+func render(src string, emit func(text string, c Class)) {
+	// a byte order mark is not a part of any token
+	src = strings.TrimPrefix(src, "\uFEFF")
+
+	var (
+		// offset and position of the code not emitted yet
+		off int
+		pos = token.Pos{Line: 1, Col: 1}
+	)
+
+	// advance moves over the code up to the position, the positions
+	// are counted like the lexer does, in runes
+	advance := func(to token.Pos) {
+		start := off
+		for off < len(src) && (pos.Line < to.Line || (pos.Line == to.Line && pos.Col < to.Col)) {
+			if src[off] == '\n' {
+				pos.Line++
+				pos.Col = 1
+				off++
+				continue
+			}
+
+			_, w := utf8.DecodeRuneInString(src[off:])
+			off += w
+			pos.Col++
+		}
+
+		if off > start {
+			emit(src[start:off], Plain)
+		}
+	}
+
+	for t := range lexer.NewWithComments(src).All() {
+		advance(t.Pos)
+
+		emit(t.Literal, ClassOf(t.Typ))
+		off += len(t.Literal)
+		pos.Col += utf8.RuneCountInString(t.Literal)
+	}
+
+	advance(token.Pos{Line: len(src) + 1})
+}
@@ -0,0 +1,71 @@
+package highlight
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+func TestHTML(t *testing.T) {
+	got := HTML("let a = 1 < b; // a<b\n@")
+
+	expected := `<pre class="monkey"><span class="keyword">let</span> <span class="ident">a</span> ` +
+		`<span class="operator">=</span> <span class="literal">1</span> <span class="operator">&lt;</span> ` +
+		`<span class="ident">b</span>; <span class="comment">// a&lt;b</span>` + "\n" +
+		`<span class="illegal">@</span></pre>` + "\n"
+
+	if got != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestANSI(t *testing.T) {
+	got := ANSI("while (x) { x -= 0.5 }")
+
+	expected := "\x1b[1;35mwhile\x1b[0m (x) { x \x1b[33m-=\x1b[0m \x1b[36m0.5\x1b[0m }"
+
+	if got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestKeepsCode(t *testing.T) {
+	tests := []string{
+		"",
+		"let café = 1;\n\n\t// über\r\n  日本語 ** 2   ",
+		"\uFEFFx \xff\xfe y $#\n",
+		"let a = [\n1]\n\n",
+	}
+
+	escapes := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	for _, src := range tests {
+		want := strings.TrimPrefix(src, "\uFEFF")
+		if got := escapes.ReplaceAllString(ANSI(src), ""); got != want {
+			t.Fatalf("Highlighting changed %q into %q", want, got)
+		}
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	tests := map[token.Typ]Class{
+		token.LET:       Keyword,
+		token.TRUE:      Keyword,
+		token.INT:       Literal,
+		token.FLOAT:     Literal,
+		token.IDENT:     Ident,
+		token.POWER:     Operator,
+		token.NOT:       Operator,
+		token.COMMENT:   Comment,
+		token.ILLEGAL:   Illegal,
+		token.SEMICOLON: Plain,
+		token.LBRACE:    Plain,
+	}
+
+	for typ, c := range tests {
+		if got := ClassOf(typ); got != c {
+			t.Fatalf("Expected %s to be %s, got %s", typ, c, got)
+		}
+	}
+}
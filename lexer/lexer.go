@@ -1,33 +1,133 @@
 package lexer
 
-import "github.com/grzkv/m-interpreter/token"
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/grzkv/m-interpreter/token"
+)
 
 // import "log"
 
-// Lexer breaks code into tokens
+// Lexer breaks code into tokens. The code is UTF-8, identifiers can have
+// any Unicode letters and digits. Columns are counted in runes
 type Lexer struct {
-	input   string
+	input []byte
+	// byte offsets of current and of the rune after it
 	pos     int
 	rPos    int
-	current byte
+	current rune
+
+	// the rest of the code for lexers made with NewReader. input then
+	// holds the code from the current token to what was read so far,
+	// the code before the token is dropped from it by discard
+	r     io.Reader
+	chunk []byte
+	err   error
+
+	// position of current
+	line int
+	col  int
+
+	// emit comments as tokens instead of skipping them
+	keepComments bool
 }
 
-// New makes a lexer
+const bom = "\uFEFF"
+
+// readerChunk is how much a NewReader lexer reads at a time
+const readerChunk = 4096
+
+// New makes a lexer. A leading byte order mark is skipped
 func New(input string) *Lexer {
-	l := Lexer{input: input}
+	input = strings.TrimPrefix(input, bom)
+
+	l := Lexer{input: []byte(input), line: 1}
+	l.readCh()
+
+	return &l
+}
+
+// NewReader makes a lexer that reads the code as it goes. Only the current
+// token and a chunk of the code after it are kept in memory. The tokens
+// are the same as New makes of the whole code
+func NewReader(r io.Reader) *Lexer {
+	l := Lexer{r: r, chunk: make([]byte, readerChunk), line: 1}
+	l.fill(len(bom))
+	l.input = bytes.TrimPrefix(l.input, []byte(bom))
 	l.readCh()
 
 	return &l
 }
 
+// Err returns the error that happened reading the code of a NewReader
+// lexer. The code ends where reading failed
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// fill reads the code until there are n bytes in the buffer or
+// the code ends
+func (l *Lexer) fill(n int) {
+	for l.r != nil && len(l.input) < n {
+		k, err := l.r.Read(l.chunk)
+		l.input = append(l.input, l.chunk[:k]...)
+
+		if err != nil {
+			if err != io.EOF {
+				l.err = err
+			}
+			l.r = nil
+		}
+	}
+}
+
+// discard drops the code before the current character from the buffer.
+// The rest is moved to the start, so that the buffer is reused
+func (l *Lexer) discard() {
+	if l.chunk == nil || l.pos == 0 {
+		return
+	}
+
+	n := copy(l.input, l.input[l.pos:])
+	l.input = l.input[:n]
+	l.rPos -= l.pos
+	l.pos = 0
+}
+
+// NewWithComments makes a lexer that returns comments as token.COMMENT
+// instead of skipping them. Used by tools that have to preserve comments
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.keepComments = true
+
+	return l
+}
+
 // NextToken gets next token from the code
 func (l *Lexer) NextToken() token.Token {
+	l.discard()
 	l.eatWhitespace()
 
+	for !l.keepComments && l.isCommentStart() {
+		l.readComment()
+		l.eatWhitespace()
+	}
+
+	pos := token.Pos{Line: l.line, Col: l.col}
+
 	var t token.Token
 	switch l.current {
 	case '+':
-		t = token.Token{Typ: token.PLUS, Literal: "+"}
+		if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.PLUS_ASSIGN, Literal: "+="}
+		} else {
+			t = token.Token{Typ: token.PLUS, Literal: "+"}
+		}
 	case '=':
 		if l.peek() == '=' {
 			l.readCh()
@@ -39,6 +139,8 @@ func (l *Lexer) NextToken() token.Token {
 		t = token.Token{Typ: token.COMMA, Literal: ","}
 	case ';':
 		t = token.Token{Typ: token.SEMICOLON, Literal: ";"}
+	case ':':
+		t = token.Token{Typ: token.COLON, Literal: ":"}
 	case '(':
 		t = token.Token{Typ: token.LPAREN, Literal: "("}
 	case ')':
@@ -47,6 +149,10 @@ func (l *Lexer) NextToken() token.Token {
 		t = token.Token{Typ: token.LBRACE, Literal: "{"}
 	case '}':
 		t = token.Token{Typ: token.RBRACE, Literal: "}"}
+	case '[':
+		t = token.Token{Typ: token.LBRACKET, Literal: "["}
+	case ']':
+		t = token.Token{Typ: token.RBRACKET, Literal: "]"}
 	case '!':
 		if l.peek() == '=' {
 			l.readCh()
@@ -55,15 +161,76 @@ func (l *Lexer) NextToken() token.Token {
 			t = token.Token{Typ: token.NOT, Literal: "!"}
 		}
 	case '-':
-		t = token.Token{Typ: token.MINUS, Literal: "-"}
+		if l.peek() == '>' {
+			l.readCh()
+			t = token.Token{Typ: token.ARROW, Literal: "->"}
+		} else if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.MINUS_ASSIGN, Literal: "-="}
+		} else {
+			t = token.Token{Typ: token.MINUS, Literal: "-"}
+		}
 	case '/':
-		t = token.Token{Typ: token.DIVIDE, Literal: "/"}
+		if l.isCommentStart() {
+			t = token.Token{Typ: token.COMMENT, Literal: l.readComment(), Pos: pos}
+			return t
+		}
+		if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.DIVIDE_ASSIGN, Literal: "/="}
+		} else {
+			t = token.Token{Typ: token.DIVIDE, Literal: "/"}
+		}
 	case '*':
-		t = token.Token{Typ: token.MULT, Literal: "*"}
+		if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.MULT_ASSIGN, Literal: "*="}
+		} else if l.peek() == '*' {
+			l.readCh()
+			t = token.Token{Typ: token.POWER, Literal: "**"}
+		} else {
+			t = token.Token{Typ: token.MULT, Literal: "*"}
+		}
 	case '<':
-		t = token.Token{Typ: token.LESS, Literal: "<"}
+		if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.LESS_EQ, Literal: "<="}
+		} else if l.peek() == '<' {
+			l.readCh()
+			t = token.Token{Typ: token.SHIFT_LEFT, Literal: "<<"}
+		} else {
+			t = token.Token{Typ: token.LESS, Literal: "<"}
+		}
 	case '>':
-		t = token.Token{Typ: token.GREATER, Literal: ">"}
+		if l.peek() == '=' {
+			l.readCh()
+			t = token.Token{Typ: token.GREATER_EQ, Literal: ">="}
+		} else if l.peek() == '>' {
+			l.readCh()
+			t = token.Token{Typ: token.SHIFT_RIGHT, Literal: ">>"}
+		} else {
+			t = token.Token{Typ: token.GREATER, Literal: ">"}
+		}
+	case '%':
+		t = token.Token{Typ: token.MOD, Literal: "%"}
+	case '&':
+		if l.peek() == '&' {
+			l.readCh()
+			t = token.Token{Typ: token.AND, Literal: "&&"}
+		} else {
+			t = token.Token{Typ: token.BIT_AND, Literal: "&"}
+		}
+	case '|':
+		if l.peek() == '|' {
+			l.readCh()
+			t = token.Token{Typ: token.OR, Literal: "||"}
+		} else {
+			t = token.Token{Typ: token.BIT_OR, Literal: "|"}
+		}
+	case '^':
+		t = token.Token{Typ: token.BIT_XOR, Literal: "^"}
+	case '~':
+		t = token.Token{Typ: token.BIT_NOT, Literal: "~"}
 	case 0:
 		t = token.Token{Typ: token.EOF, Literal: ""}
 	default:
@@ -71,7 +238,7 @@ func (l *Lexer) NextToken() token.Token {
 			word := l.readWord()
 			// log.Printf("read word %s", word)
 
-			kw, prs := keywords[word]
+			kw, prs := token.LookupKeyword(word)
 
 			if prs {
 				// log.Println("classified word as a keyword")
@@ -79,31 +246,99 @@ func (l *Lexer) NextToken() token.Token {
 			} else {
 				t = token.Token{Typ: token.IDENT, Literal: word}
 			}
-		} else if isDigit(l.current) {
-			number := l.readNumber()
+		} else if isDigit(l.current) || (l.current == '.' && isDigit(l.peek())) {
+			// .5 is read as a float for the parser to report it
+			typ, number := l.readNumber()
 			// log.Printf("read number %s", number)
 
-			t = token.Token{Typ: token.INT, Literal: number}
+			t = token.Token{Typ: typ, Literal: number}
+		} else if l.isInvalid() {
+			t = token.Token{Typ: token.ILLEGAL, Literal: l.readInvalid()}
 		} else {
-			t = token.Token{Typ: token.ILLEGAL, Literal: ""}
+			t = token.Token{Typ: token.ILLEGAL, Literal: l.readIllegal()}
 		}
 
+		t.Pos = pos
 		return t
 	}
 
+	t.Pos = pos
 	l.readCh()
 	return t
 }
 
 func (l *Lexer) readCh() {
+	if l.current == '\n' {
+		l.line++
+		l.col = 0
+	}
+	l.fill(l.rPos + utf8.UTFMax)
+	if l.rPos <= len(l.input) {
+		l.col++
+	}
+
 	if l.rPos >= len(l.input) {
+		// rPos past the end tells that the end was reached already
+		l.pos = len(l.input)
+		l.rPos = len(l.input) + 1
 		l.current = 0
-	} else {
-		l.current = l.input[l.rPos]
+		return
 	}
 
 	l.pos = l.rPos
-	l.rPos++
+
+	var w int
+	l.current, w = decodeRune(l.input[l.rPos:])
+	l.rPos += w
+}
+
+// decodeRune decodes the first rune, ASCII without calling utf8
+func decodeRune(b []byte) (rune, int) {
+	if c := b[0]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+
+	return utf8.DecodeRune(b)
+}
+
+// isInvalid tells if the current byte is not valid UTF-8. A correctly
+// encoded U+FFFD is three bytes long
+func (l *Lexer) isInvalid() bool {
+	return l.current == utf8.RuneError && l.rPos-l.pos == 1
+}
+
+// tokenChars are the characters that start the operators and delimiters
+// in NextToken
+const tokenChars = "+=,;:(){}[]!-/*<>%&|^~"
+
+// startsToken tells if a token other than ILLEGAL starts at the current
+// character
+func (l *Lexer) startsToken() bool {
+	return l.current == 0 || isWhitespace(l.current) || isLetter(l.current) ||
+		isDigit(l.current) || (l.current == '.' && isDigit(l.peek())) ||
+		strings.ContainsRune(tokenChars, l.current) || l.isInvalid()
+}
+
+// readIllegal reads a run of characters that start no token, at least
+// the current one
+func (l *Lexer) readIllegal() string {
+	start := l.pos
+	l.readCh()
+	for !l.startsToken() {
+		l.readCh()
+	}
+
+	return string(l.input[start:l.pos])
+}
+
+// readInvalid reads a run of bytes which are not valid UTF-8
+func (l *Lexer) readInvalid() string {
+	start := l.pos
+	for l.isInvalid() {
+		l.readCh()
+	}
+
+	return string(l.input[start:l.pos])
 }
 
 func (l *Lexer) eatWhitespace() {
@@ -112,53 +347,119 @@ func (l *Lexer) eatWhitespace() {
 	}
 }
 
-func (l Lexer) peek() byte {
+func (l *Lexer) peek() rune {
+	l.fill(l.rPos + utf8.UTFMax)
 	if l.rPos >= len(l.input) {
 		return 0
 	}
-	return l.input[l.rPos]
+
+	r, _ := decodeRune(l.input[l.rPos:])
+	return r
 }
 
+// readWord reads an identifier or a keyword: a letter followed by
+// letters and digits
 func (l *Lexer) readWord() string {
 	firstLetterPos := l.pos
-	for isLetter(l.current) {
+	for isLetter(l.current) || isIdentDigit(l.current) {
 		l.readCh()
 	}
 
-	return l.input[firstLetterPos:l.pos]
+	return string(l.input[firstLetterPos:l.pos])
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) isCommentStart() bool {
+	return l.current == '/' && l.peek() == '/'
+}
+
+// readComment reads a // comment up to, but not including, the line end
+func (l *Lexer) readComment() string {
+	start := l.pos
+	for l.current != '\n' && l.current != 0 {
+		l.readCh()
+	}
+
+	return string(l.input[start:l.pos])
+}
+
+// readNumber reads an integer or a float literal. A float has a fraction
+// after the point, an exponent or both: 3.14, 1e-9, 2.5E+3. Integers can
+// have a base prefix: 0xFF, 0o17, 0b101. Digits can be separated by _.
+// Literals are read whole even if malformed, so that the parser reports
+// 1e, 0x or 1__0 as they are
+func (l *Lexer) readNumber() (token.Typ, string) {
 	start := l.pos
-	for isDigit(l.current) {
+	typ := token.Typ(token.INT)
+
+	if l.current == '0' && isBasePrefix(l.peek()) {
+		l.readCh()
+		l.readCh()
+		for isLetter(l.current) || isDigit(l.current) {
+			l.readCh()
+		}
+		return typ, string(l.input[start:l.pos])
+	}
+
+	l.readDigits()
+	if l.current == '.' && isDigit(l.peek()) {
+		typ = token.FLOAT
+		l.readCh()
+		l.readDigits()
+	}
+
+	if l.current == 'e' || l.current == 'E' {
+		typ = token.FLOAT
 		l.readCh()
+		if l.current == '+' || l.current == '-' {
+			l.readCh()
+		}
+		l.readDigits()
 	}
 
-	return l.input[start:l.pos]
+	return typ, string(l.input[start:l.pos])
 }
 
-var keywords = map[string]token.Typ{
-	"fn":     token.FUNCTION,
-	"let":    token.LET,
-	"if":     token.IF,
-	"else":   token.ELSE,
-	"return": token.RETURN,
-	"false":  token.FALSE,
-	"true":   token.TRUE,
+// readDigits reads decimal digits and the separators between them
+func (l *Lexer) readDigits() {
+	for isDigit(l.current) || l.current == '_' {
+		l.readCh()
+	}
 }
 
 // char utils
 
-func isLetter(c byte) bool {
-	return (c >= 'a' && c <= 'z') ||
-		(c >= 'A' && c <= 'Z') ||
-		c == '_'
+func isLetter(c rune) bool {
+	if c < utf8.RuneSelf {
+		return (c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			c == '_'
+	}
+
+	return unicode.IsLetter(c)
 }
 
-func isDigit(c byte) bool {
+// isDigit is for numbers, which are ASCII only
+func isDigit(c rune) bool {
 	return (c >= '0' && c <= '9')
 }
 
-func isWhitespace(c byte) bool {
+// isIdentDigit is for identifiers, which can have any Unicode digits
+func isIdentDigit(c rune) bool {
+	if c < utf8.RuneSelf {
+		return isDigit(c)
+	}
+
+	return unicode.IsDigit(c)
+}
+
+func isBasePrefix(c rune) bool {
+	switch c {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isWhitespace(c rune) bool {
 	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
 }
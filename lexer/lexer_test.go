@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"io"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	. "github.com/grzkv/m-interpreter/token"
 )
@@ -19,8 +23,7 @@ func TestNextTokenStarter(t *testing.T) {
 		{RPAREN, ")"},
 		{LBRACE, "{"},
 		{RBRACE, "}"},
-		{PLUS, "+"},
-		{ASSIGN, "="},
+		{PLUS_ASSIGN, "+="},
 		{COMMA, ","},
 		{SEMICOLON, ";"},
 	}
@@ -157,8 +160,454 @@ func runLexerTest(t *testing.T, input string, tests []ExpToken) {
 
 		if tk.Literal != tt.expLiteral {
 			t.Fatalf("Test %d failed. Expected token literal %q - got %q", i,
-				tt.expTyp, tk.Typ)
+				tt.expLiteral, tk.Literal)
+		}
+
+	}
+}
+
+func TestAnnotationTokens(t *testing.T) {
+	input := `let f: fn([int]) -> {a: b} = g; a - >b`
+
+	tests := []ExpToken{
+		{LET, "let"},
+		{IDENT, "f"},
+		{COLON, ":"},
+		{FUNCTION, "fn"},
+		{LPAREN, "("},
+		{LBRACKET, "["},
+		{IDENT, "int"},
+		{RBRACKET, "]"},
+		{RPAREN, ")"},
+		{ARROW, "->"},
+		{LBRACE, "{"},
+		{IDENT, "a"},
+		{COLON, ":"},
+		{IDENT, "b"},
+		{RBRACE, "}"},
+		{ASSIGN, "="},
+		{IDENT, "g"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{MINUS, "-"},
+		{GREATER, ">"},
+		{IDENT, "b"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestLoopKeywords(t *testing.T) {
+	input := `while for in break continue inside`
+
+	tests := []ExpToken{
+		{WHILE, "while"},
+		{FOR, "for"},
+		{IN, "in"},
+		{BREAK, "break"},
+		{CONTINUE, "continue"},
+		{IDENT, "inside"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+	let a = 1; // trailing
+	a / 2;`
+
+	tests := []ExpToken{
+		{LET, "let"},
+		{IDENT, "a"},
+		{ASSIGN, "="},
+		{INT, "1"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{DIVIDE, "/"},
+		{INT, "2"},
+		{SEMICOLON, ";"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+
+	l := NewWithComments(input)
+	for _, exp := range []ExpToken{{COMMENT, "// leading"}, {LET, "let"}} {
+		if tk := l.NextToken(); tk.Typ != exp.expTyp || tk.Literal != exp.expLiteral {
+			t.Fatalf("Expected %q %q, got %q %q", exp.expTyp, exp.expLiteral, tk.Typ, tk.Literal)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "let a = 10;\n  a == 1"
+
+	tests := []Pos{
+		{Line: 1, Col: 1},
+		{Line: 1, Col: 5},
+		{Line: 1, Col: 7},
+		{Line: 1, Col: 9},
+		{Line: 1, Col: 11},
+		{Line: 2, Col: 3},
+		{Line: 2, Col: 5},
+		{Line: 2, Col: 8},
+		{Line: 2, Col: 9},
+	}
+
+	l := New(input)
+	for i, exp := range tests {
+		tk := l.NextToken()
+		if tk.Pos != exp {
+			t.Fatalf("Test %d failed. Expected position %s of %q, got %s", i, exp, tk.Literal, tk.Pos)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	input := "a && b || !c & d | e"
+
+	tests := []ExpToken{
+		{IDENT, "a"},
+		{AND, "&&"},
+		{IDENT, "b"},
+		{OR, "||"},
+		{NOT, "!"},
+		{IDENT, "c"},
+		{BIT_AND, "&"},
+		{IDENT, "d"},
+		{BIT_OR, "|"},
+		{IDENT, "e"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestArithmeticOperators(t *testing.T) {
+	input := "a <= b >= c % d ** e ^ ~f << g >> h < i > j * k *= l"
+
+	tests := []ExpToken{
+		{IDENT, "a"},
+		{LESS_EQ, "<="},
+		{IDENT, "b"},
+		{GREATER_EQ, ">="},
+		{IDENT, "c"},
+		{MOD, "%"},
+		{IDENT, "d"},
+		{POWER, "**"},
+		{IDENT, "e"},
+		{BIT_XOR, "^"},
+		{BIT_NOT, "~"},
+		{IDENT, "f"},
+		{SHIFT_LEFT, "<<"},
+		{IDENT, "g"},
+		{SHIFT_RIGHT, ">>"},
+		{IDENT, "h"},
+		{LESS, "<"},
+		{IDENT, "i"},
+		{GREATER, ">"},
+		{IDENT, "j"},
+		{MULT, "*"},
+		{IDENT, "k"},
+		{MULT_ASSIGN, "*="},
+		{IDENT, "l"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestNumbers(t *testing.T) {
+	input := "3.14 1e-9 2.5E+3 7 .5 1e x"
+
+	tests := []ExpToken{
+		{FLOAT, "3.14"},
+		{FLOAT, "1e-9"},
+		{FLOAT, "2.5E+3"},
+		{INT, "7"},
+		{FLOAT, ".5"},
+		{FLOAT, "1e"},
+		{IDENT, "x"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestIntegerBases(t *testing.T) {
+	input := "0xFF 0o17 0b1010 1_000_000 0x 1__0 0b12 0XdEaD_bEeF 1_000.5 0 07"
+
+	tests := []ExpToken{
+		{INT, "0xFF"},
+		{INT, "0o17"},
+		{INT, "0b1010"},
+		{INT, "1_000_000"},
+		{INT, "0x"},
+		{INT, "1__0"},
+		{INT, "0b12"},
+		{INT, "0XdEaD_bEeF"},
+		{FLOAT, "1_000.5"},
+		{INT, "0"},
+		{INT, "07"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestAssignOperators(t *testing.T) {
+	input := "a += 1; a -= b; a *= 2; a /= 3; a[0] = -1;"
+
+	tests := []ExpToken{
+		{IDENT, "a"},
+		{PLUS_ASSIGN, "+="},
+		{INT, "1"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{MINUS_ASSIGN, "-="},
+		{IDENT, "b"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{MULT_ASSIGN, "*="},
+		{INT, "2"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{DIVIDE_ASSIGN, "/="},
+		{INT, "3"},
+		{SEMICOLON, ";"},
+		{IDENT, "a"},
+		{LBRACKET, "["},
+		{INT, "0"},
+		{RBRACKET, "]"},
+		{ASSIGN, "="},
+		{MINUS, "-"},
+		{INT, "1"},
+		{SEMICOLON, ";"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestUnicode(t *testing.T) {
+	input := "\uFEFFlet café = x1 + π;\n  日本語 == été2; \xff\xfe a"
+
+	tests := []struct {
+		typ     Typ
+		literal string
+		pos     Pos
+	}{
+		{LET, "let", Pos{Line: 1, Col: 1}},
+		{IDENT, "café", Pos{Line: 1, Col: 5}},
+		{ASSIGN, "=", Pos{Line: 1, Col: 10}},
+		{IDENT, "x1", Pos{Line: 1, Col: 12}},
+		{PLUS, "+", Pos{Line: 1, Col: 15}},
+		{IDENT, "π", Pos{Line: 1, Col: 17}},
+		{SEMICOLON, ";", Pos{Line: 1, Col: 18}},
+		{IDENT, "日本語", Pos{Line: 2, Col: 3}},
+		{EQ, "==", Pos{Line: 2, Col: 7}},
+		{IDENT, "été2", Pos{Line: 2, Col: 10}},
+		{SEMICOLON, ";", Pos{Line: 2, Col: 14}},
+		{ILLEGAL, "\xff\xfe", Pos{Line: 2, Col: 16}},
+		{IDENT, "a", Pos{Line: 2, Col: 19}},
+		{EOF, "", Pos{Line: 2, Col: 20}},
+	}
+
+	l := New(input)
+	for i, exp := range tests {
+		tk := l.NextToken()
+		if tk.Typ != exp.typ || tk.Literal != exp.literal || tk.Pos != exp.pos {
+			t.Fatalf("Test %d failed. Expected %q %q at %s, got %q %q at %s",
+				i, exp.typ, exp.literal, exp.pos, tk.Typ, tk.Literal, tk.Pos)
+		}
+	}
+}
+
+func TestIllegal(t *testing.T) {
+	input := "a @ b $#? c 1.x ١ @+"
+
+	tests := []ExpToken{
+		{IDENT, "a"},
+		{ILLEGAL, "@"},
+		{IDENT, "b"},
+		{ILLEGAL, "$#?"},
+		{IDENT, "c"},
+		{INT, "1"},
+		{ILLEGAL, "."},
+		{IDENT, "x"},
+		{ILLEGAL, "١"},
+		{ILLEGAL, "@"},
+		{PLUS, "+"},
+		{EOF, ""},
+	}
+
+	runLexerTest(t, input, tests)
+}
+
+func TestNewReaderSameTokens(t *testing.T) {
+	long := strings.Repeat("ü", readerChunk) + "x"
+
+	inputs := []string{
+		"",
+		"let five = 5;\nlet add = fn(x, y) { x + y; };\n",
+		"\uFEFFlet café = 0xFF_FF + 1.5e-3; // über\n日本語 <<= a ** b",
+		"a @ b $#? \xff\xfe c .5 1. 0b12",
+		"// " + long + "\n" + long + " = " + strings.Repeat("9", 3*readerChunk) + ";",
+		"\uFEFF",
+		"x\n\n\n",
+	}
+
+	readers := map[string]func(string) io.Reader{
+		"whole":    func(s string) io.Reader { return strings.NewReader(s) },
+		"one byte": func(s string) io.Reader { return iotest.OneByteReader(strings.NewReader(s)) },
+		"half":     func(s string) io.Reader { return iotest.HalfReader(strings.NewReader(s)) },
+	}
+
+	for _, in := range inputs {
+		for name, reader := range readers {
+			sl, rl := New(in), NewReader(reader(in))
+
+			for i := 0; ; i++ {
+				exp, got := sl.NextToken(), rl.NextToken()
+				if exp != got {
+					t.Fatalf("Token %d of %q differs with %s reader: expected %v, got %v", i, in, name, exp, got)
+				}
+				if exp.Typ == EOF {
+					break
+				}
+			}
+
+			if rl.Err() != nil {
+				t.Fatalf("Unexpected error reading %q: %v", in, rl.Err())
+			}
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("let a"), iotest.ErrReader(iotest.ErrTimeout))
+	l := NewReader(r)
+
+	for _, exp := range []ExpToken{{LET, "let"}, {IDENT, "a"}, {EOF, ""}} {
+		if tk := l.NextToken(); tk.Typ != exp.expTyp || tk.Literal != exp.expLiteral {
+			t.Fatalf("Expected %q %q, got %q %q", exp.expTyp, exp.expLiteral, tk.Typ, tk.Literal)
+		}
+	}
+
+	if l.Err() != iotest.ErrTimeout {
+		t.Fatalf("Expected timeout error, got %v", l.Err())
+	}
+}
+
+func TestNewReaderBuffer(t *testing.T) {
+	l := NewReader(strings.NewReader(strings.Repeat("let a = b + 1; // x\n", 10000)))
+
+	for tk := l.NextToken(); tk.Typ != EOF; tk = l.NextToken() {
+		if len(l.input) > 2*readerChunk {
+			t.Fatalf("Buffer grew to %d bytes at %s", len(l.input), tk.Pos)
+		}
+	}
+}
+
+func TestNewReaderLongToken(t *testing.T) {
+	const size = 8 << 20
+	name := strings.Repeat("a", size)
+	r := strings.NewReader(name + ";")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	l := NewReader(r)
+	tk := l.NextToken()
+
+	runtime.ReadMemStats(&after)
+
+	if tk.Typ != IDENT || tk.Literal != name {
+		t.Fatalf("Expected identifier of %d bytes, got %s of %d", size, tk.Typ, len(tk.Literal))
+	}
+
+	// the buffer doubles as it grows, and the literal is one more copy
+	if n := after.TotalAlloc - before.TotalAlloc; n > 6*size {
+		t.Fatalf("Lexing a token of %d bytes allocated %d bytes", size, n)
+	}
+}
+
+func TestIllegalError(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"let a = 1;\n\nlet b = @;", "unexpected character '@' at 3:9"},
+		{"x $#?", "unexpected characters '$#?' at 1:3"},
+		{"é ١", "unexpected character '١' at 1:3"},
+		{"a \xff\xfe", "invalid UTF-8 encoding at 1:3"},
+	}
+
+	for _, tst := range tests {
+		var errs []string
+		for tok := range New(tst.in).All() {
+			if tok.Typ == ILLEGAL {
+				errs = append(errs, IllegalError(tok).Error())
+			}
 		}
 
+		if len(errs) != 1 || errs[0] != tst.expected {
+			t.Fatalf("Lexing %q: expected error %q, got %q", tst.in, tst.expected, errs)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, errs := Tokenize("let a = 1; // one\na @ $$")
+
+	expected := []ExpToken{
+		{LET, "let"},
+		{IDENT, "a"},
+		{ASSIGN, "="},
+		{INT, "1"},
+		{SEMICOLON, ";"},
+		{COMMENT, "// one"},
+		{IDENT, "a"},
+		{ILLEGAL, "@"},
+		{ILLEGAL, "$$"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %v", len(expected), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i].Typ != exp.expTyp || tokens[i].Literal != exp.expLiteral {
+			t.Fatalf("Token %d: expected %q %q, got %q %q", i, exp.expTyp, exp.expLiteral, tokens[i].Typ, tokens[i].Literal)
+		}
+	}
+
+	if len(errs) != 2 || errs[0].Error() != "unexpected character '@' at 2:3" || errs[1].Error() != "unexpected characters '$$' at 2:5" {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+}
+
+func TestAll(t *testing.T) {
+	l := New("a + b; c")
+
+	var literals []string
+	for tk := range l.All() {
+		literals = append(literals, tk.Literal)
+		if tk.Typ == SEMICOLON {
+			break
+		}
+	}
+
+	if strings.Join(literals, " ") != "a + b ;" {
+		t.Fatalf("Unexpected tokens %v", literals)
+	}
+
+	// the iteration goes on from where it stopped
+	for tk := range l.All() {
+		if tk.Literal != "c" {
+			t.Fatalf("Expected c, got %q", tk.Literal)
+		}
 	}
 }
@@ -0,0 +1,65 @@
+package lexer
+
+import (
+	"iter"
+	"unicode/utf8"
+
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Error is text of the code that makes no token
+type Error struct {
+	Pos token.Pos
+	Msg string
+}
+
+// Error gives the message followed by the position, e.g.
+// "unexpected character '@' at 3:7"
+func (e Error) Error() string {
+	return e.Msg + " at " + e.Pos.String()
+}
+
+// IllegalError describes what is wrong with the ILLEGAL token
+func IllegalError(t token.Token) Error {
+	e := Error{Pos: t.Pos}
+
+	switch {
+	case !utf8.ValidString(t.Literal):
+		e.Msg = "invalid UTF-8 encoding"
+	case utf8.RuneCountInString(t.Literal) == 1:
+		e.Msg = "unexpected character '" + t.Literal + "'"
+	default:
+		e.Msg = "unexpected characters '" + t.Literal + "'"
+	}
+
+	return e
+}
+
+// All iterates over the rest of the tokens, up to but not including EOF
+func (l *Lexer) All() iter.Seq[token.Token] {
+	return func(yield func(token.Token) bool) {
+		for t := l.NextToken(); t.Typ != token.EOF; t = l.NextToken() {
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Tokenize breaks the whole code into tokens, comments included. ILLEGAL
+// tokens are kept in the list and reported as errors
+func Tokenize(src string) ([]token.Token, []error) {
+	var (
+		tokens []token.Token
+		errs   []error
+	)
+
+	for t := range NewWithComments(src).All() {
+		tokens = append(tokens, t)
+		if t.Typ == token.ILLEGAL {
+			errs = append(errs, IllegalError(t))
+		}
+	}
+
+	return tokens, errs
+}
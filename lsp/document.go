@@ -0,0 +1,168 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+	"github.com/grzkv/m-interpreter/resolver"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+const bom = "\uFEFF"
+
+// document is an open file with the results of its analysis
+type document struct {
+	uri  string
+	text string
+	// lines of the text without a leading BOM, which the lexer skips
+	// but the client counts as a character of the first line
+	lines []string
+	bom   bool
+
+	prg  *ast.Program
+	errs []parser.Error
+	info *resolver.Info
+
+	// every identifier, declared or used, in source order
+	idents []*ast.IdentifierEx
+}
+
+func newDocument(uri, text string) *document {
+	p := parser.New(lexer.New(text))
+
+	d := &document{
+		uri:   uri,
+		text:  text,
+		lines: strings.Split(strings.TrimPrefix(text, bom), "\n"),
+		bom:   strings.HasPrefix(text, bom),
+		prg:   p.Parse(),
+		errs:  p.ErrorList(),
+	}
+	d.info = resolver.Resolve(d.prg)
+
+	ast.Inspect(d.prg, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentifierEx); ok {
+			d.idents = append(d.idents, id)
+		}
+		return true
+	})
+
+	return d
+}
+
+// letOf returns the let statement that declares the identifier
+func (d *document) letOf(id *ast.IdentifierEx) *ast.LetSt {
+	decl := d.info.DeclOf(id)
+	if decl == nil {
+		return nil
+	}
+
+	let, _ := decl.Node.(*ast.LetSt)
+	return let
+}
+
+// identAt finds the identifier under the position
+func (d *document) identAt(pos token.Pos) *ast.IdentifierEx {
+	for _, id := range d.idents {
+		start := id.Token.Pos
+		end := start.Col + utf8.RuneCountInString(id.Value)
+		if start.Line == pos.Line && start.Col <= pos.Col && pos.Col <= end {
+			return id
+		}
+	}
+
+	return nil
+}
+
+// Positions of tokens count characters, LSP ones count UTF-16 code units,
+// e.g. 2 for an emoji. The conversions go through the text of the line
+
+// line returns the text of the line, 0-based as in LSP
+func (d *document) line(n int) string {
+	if n < 0 || n >= len(d.lines) {
+		return ""
+	}
+
+	return d.lines[n]
+}
+
+// lineStart is the number of UTF-16 units before the first character
+// of the line: one for the BOM on the first line, otherwise none
+func (d *document) lineStart(n int) int {
+	if n == 0 && d.bom {
+		return 1
+	}
+
+	return 0
+}
+
+// endPosition is the position right after the last character
+func (d *document) endPosition() Position {
+	last := len(d.lines) - 1
+
+	return Position{Line: last, Character: d.lineStart(last) + utf16Len(d.lines[last])}
+}
+
+// toPosition converts token position to LSP one. Both lines and columns of
+// tokens start at 1, LSP counts from 0. Columns past the end of the line
+// count as one unit each
+func (d *document) toPosition(pos token.Pos) Position {
+	p := Position{Line: pos.Line - 1}
+	p.Character = d.lineStart(p.Line)
+
+	chars := pos.Col - 1
+	for _, r := range d.line(p.Line) {
+		if chars == 0 {
+			break
+		}
+		p.Character += utf16.RuneLen(r)
+		chars--
+	}
+	p.Character += chars
+
+	return p
+}
+
+// toTokenPos converts LSP position to token one. A position inside
+// a character is the position of the character
+func (d *document) toTokenPos(p Position) token.Pos {
+	pos := token.Pos{Line: p.Line + 1, Col: 1}
+
+	units := p.Character - d.lineStart(p.Line)
+	for _, r := range d.line(p.Line) {
+		if units <= 0 {
+			break
+		}
+		units -= utf16.RuneLen(r)
+		pos.Col++
+	}
+	if units > 0 {
+		pos.Col += units
+	}
+
+	return pos
+}
+
+func (d *document) tokenRange(t token.Token) Range {
+	end := t.Pos
+	end.Col += utf8.RuneCountInString(t.Literal)
+
+	return Range{Start: d.toPosition(t.Pos), End: d.toPosition(end)}
+}
+
+func (d *document) identRange(id *ast.IdentifierEx) Range {
+	return d.tokenRange(id.Token)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+
+	return n
+}
@@ -0,0 +1,456 @@
+// Package lsp is a Language Server Protocol server for Monkey. It talks
+// JSON-RPC over a pair of streams, normally stdin and stdout of an editor
+// subprocess. Documents are synchronized in full on every change
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/format"
+	"github.com/grzkv/m-interpreter/resolver"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// ErrNoShutdown is returned by Run if the client sent exit without shutdown
+var ErrNoShutdown = errors.New("exit without shutdown")
+
+// Server serves a single client
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document
+	shutdown bool
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var requestHandlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/references":     (*Server).references,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/formatting":     (*Server).formatting,
+}
+
+var notificationHandlers = map[string]func(s *Server, params json.RawMessage) error{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// NewServer makes a server reading the client messages from in
+// and writing responses and notifications to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]*document),
+	}
+}
+
+// Run serves the client until the exit notification or the end of input
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			var rpcErr *rpcError
+			if errors.As(err, &rpcErr) {
+				if err := s.reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+					return err
+				}
+				continue
+			}
+
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a message. Only failures to write are returned,
+// the rest are reported to the client
+func (s *Server) handle(msg *message) error {
+	if msg.ID == nil {
+		if h, ok := notificationHandlers[msg.Method]; ok {
+			return h(s, msg.Params)
+		}
+		// unknown notifications, like $/cancelRequest, are ignored
+		return nil
+	}
+
+	if s.shutdown {
+		return s.reply(msg.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"})
+	}
+
+	h, ok := requestHandlers[msg.Method]
+	if !ok {
+		return s.reply(msg.ID, nil, &rpcError{Code: codeMethodNotFound, Message: "unknown method " + msg.Method})
+	}
+
+	res, err := h(s, msg.Params)
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.reply(msg.ID, nil, rpcErr)
+	}
+
+	return s.reply(msg.ID, res, nil)
+}
+
+func (s *Server) reply(id json.RawMessage, res interface{}, rpcErr *rpcError) error {
+	msg := &message{ID: id, Error: rpcErr}
+
+	if rpcErr == nil {
+		data, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		msg.Result = data
+	}
+
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(s.out, &message{Method: method, Params: data})
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			// the default, columns count UTF-16 code units
+			"positionEncoding": "utf-16",
+			// full document sync
+			"textDocumentSync":           1,
+			"documentSymbolProvider":     true,
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"hoverProvider":              true,
+			"completionProvider":         map[string]interface{}{},
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "monkey"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	var p DidOpenTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	return s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	var p DidChangeTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+
+	// full sync, the last change has the whole text
+	return s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	var p DidCloseTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	delete(s.docs, p.TextDocument.URI)
+
+	// clear the diagnostics of the closed document
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// update reanalyzes the document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+
+	diags := []Diagnostic{}
+	for _, e := range doc.errs {
+		end := e.Pos
+		end.Col++
+
+		diags = append(diags, Diagnostic{
+			Range:    Range{Start: doc.toPosition(e.Pos), End: doc.toPosition(end)},
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  e.Msg,
+		})
+	}
+
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, errors.New("unknown document " + uri)
+	}
+
+	return doc, nil
+}
+
+// positionDocument decodes the params pointing into a document.
+// The position is converted to the token one
+func (s *Server) positionDocument(params json.RawMessage) (*document, token.Pos, error) {
+	var p TextDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, token.Pos{}, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, token.Pos{}, err
+	}
+
+	return doc, doc.toTokenPos(p.Position), nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := []DocumentSymbol{}
+	for _, decl := range doc.info.Decls {
+		let, ok := decl.Node.(*ast.LetSt)
+		if !ok {
+			continue
+		}
+
+		r := doc.identRange(let.Ident)
+		symbols = append(symbols, DocumentSymbol{
+			Name:           let.Ident.Value,
+			Detail:         format.Node(let.Expr),
+			Kind:           SymbolKindVariable,
+			Range:          Range{Start: doc.toPosition(let.Token.Pos), End: r.End},
+			SelectionRange: r,
+		})
+	}
+
+	return symbols, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	doc, pos, err := s.positionDocument(params)
+	if err != nil {
+		return nil, err
+	}
+
+	id := doc.identAt(pos)
+	if id == nil || doc.info.DeclOf(id) == nil {
+		return nil, nil
+	}
+
+	return Location{URI: doc.uri, Range: doc.identRange(doc.info.DeclOf(id).Ident)}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	locs := []Location{}
+
+	id := doc.identAt(doc.toTokenPos(p.Position))
+	if id == nil || doc.info.DeclOf(id) == nil {
+		return locs, nil
+	}
+	decl := doc.info.DeclOf(id)
+
+	if p.Context.IncludeDeclaration {
+		locs = append(locs, Location{URI: doc.uri, Range: doc.identRange(decl.Ident)})
+	}
+	for _, ref := range decl.Uses {
+		locs = append(locs, Location{URI: doc.uri, Range: doc.identRange(ref)})
+	}
+
+	return locs, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	doc, pos, err := s.positionDocument(params)
+	if err != nil {
+		return nil, err
+	}
+
+	id := doc.identAt(pos)
+	if id == nil || doc.letOf(id) == nil {
+		return nil, nil
+	}
+
+	r := doc.identRange(id)
+
+	return Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: "```monkey\n" + format.Node(doc.letOf(id)) + "\n```",
+		},
+		Range: &r,
+	}, nil
+}
+
+// completion suggests the keywords and the names visible at the position:
+// the let bindings made before it and the loop variables of the loops
+// around it
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	doc, pos, err := s.positionDocument(params)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := wordBefore(doc.line(pos.Line-1), pos.Col)
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+
+	// the innermost and the latest binding goes first
+	for scope := scopeAt(doc.info.Root, pos); scope != nil; scope = scope.Parent {
+		for i := len(scope.Decls) - 1; i >= 0; i-- {
+			decl := scope.Decls[i]
+			if seen[decl.Name] || !strings.HasPrefix(decl.Name, prefix) {
+				continue
+			}
+
+			var detail string
+			switch n := decl.Node.(type) {
+			case *ast.LetSt:
+				if !before(n.Token.Pos, pos) {
+					continue
+				}
+				detail = format.Node(n)
+			case *ast.ForSt:
+				detail = "for (" + decl.Name + " in " + format.Node(n.Collection) + ")"
+			}
+			seen[decl.Name] = true
+
+			items = append(items, CompletionItem{Label: decl.Name, Kind: CompletionKindVariable, Detail: detail})
+		}
+	}
+
+	for _, kw := range token.Keywords() {
+		if strings.HasPrefix(kw, prefix) {
+			items = append(items, CompletionItem{Label: kw, Kind: CompletionKindKeyword})
+		}
+	}
+
+	return items, nil
+}
+
+// scopeAt finds the innermost scope around the position
+func scopeAt(scope *resolver.Scope, pos token.Pos) *resolver.Scope {
+	for _, child := range scope.Children {
+		b, ok := child.Node.(*ast.BlockSt)
+		if ok && before(b.Token.Pos, pos) && !before(b.Rbrace.Pos, pos) {
+			return scopeAt(child, pos)
+		}
+	}
+
+	return scope
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+	var p DocumentFormattingParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(doc.text)
+	if err != nil || formatted == doc.text {
+		// code with errors is left as is, the errors are in the diagnostics
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{
+		Range:   Range{End: doc.endPosition()},
+		NewText: formatted,
+	}}, nil
+}
+
+// wordBefore returns the identifier characters of the line right before
+// the column
+func wordBefore(line string, col int) string {
+	chars := []rune(line)
+
+	end := col - 1
+	if end > len(chars) {
+		end = len(chars)
+	}
+
+	start := end
+	for start > 0 && isIdentChar(chars[start-1]) {
+		start--
+	}
+
+	return string(chars[start:end])
+}
+
+// isIdentChar tells if the character can be in an identifier, see the lexer
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// before tells if the first position is before the second
+func before(a, b token.Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
@@ -0,0 +1,433 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeClient talks to a server running in the same process
+type fakeClient struct {
+	t *testing.T
+
+	toServer   *io.PipeWriter
+	fromServer *bufio.Reader
+
+	nextID int
+	// notifications received while waiting for responses
+	notifications []*message
+
+	done chan error
+}
+
+func newFakeClient(t *testing.T) *fakeClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &fakeClient{
+		t:          t,
+		toServer:   inW,
+		fromServer: bufio.NewReader(outR),
+		done:       make(chan error, 1),
+	}
+
+	go func() {
+		err := NewServer(inR, outW).Run()
+		outW.Close()
+		c.done <- err
+	}()
+
+	c.call("initialize", map[string]interface{}{}, nil)
+	c.notify("initialized", map[string]interface{}{})
+
+	return c
+}
+
+func (c *fakeClient) send(msg *message) {
+	if err := writeMessage(c.toServer, msg); err != nil {
+		c.t.Fatalf("Sending to server failed: %v", err)
+	}
+}
+
+func (c *fakeClient) notify(method string, params interface{}) {
+	data, _ := json.Marshal(params)
+	c.send(&message{Method: method, Params: data})
+}
+
+// call sends a request and decodes the result into res
+func (c *fakeClient) call(method string, params interface{}, res interface{}) {
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+
+	data, _ := json.Marshal(params)
+	c.send(&message{ID: id, Method: method, Params: data})
+
+	for {
+		msg, err := readMessage(c.fromServer)
+		if err != nil {
+			c.t.Fatalf("Reading from server failed: %v", err)
+		}
+
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+
+		if string(msg.ID) != string(id) {
+			c.t.Fatalf("Expected response to %s, got to %s", id, msg.ID)
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("Request %s failed: %v", method, msg.Error)
+		}
+		if res != nil {
+			if err := json.Unmarshal(msg.Result, res); err != nil {
+				c.t.Fatalf("Bad result of %s: %v", method, err)
+			}
+		}
+		return
+	}
+}
+
+// diagnostics waits for the next diagnostics notification
+func (c *fakeClient) diagnostics() PublishDiagnosticsParams {
+	var msg *message
+	if len(c.notifications) != 0 {
+		msg, c.notifications = c.notifications[0], c.notifications[1:]
+	} else {
+		var err error
+		if msg, err = readMessage(c.fromServer); err != nil {
+			c.t.Fatalf("Reading from server failed: %v", err)
+		}
+	}
+
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("Expected diagnostics, got %s", msg.Method)
+	}
+
+	var p PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		c.t.Fatalf("Bad diagnostics: %v", err)
+	}
+
+	return p
+}
+
+func (c *fakeClient) open(uri, text string) PublishDiagnosticsParams {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text},
+	})
+
+	return c.diagnostics()
+}
+
+func (c *fakeClient) close() {
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+
+	if err := <-c.done; err != nil {
+		c.t.Fatalf("Server failed: %v", err)
+	}
+}
+
+func positionParams(uri string, line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: char},
+	}
+}
+
+const testURI = "file:///test.mk"
+
+const testDoc = `let a = 1;
+let b = a + 2;
+let a = a * b;
+a - b;
+`
+
+func TestDiagnostics(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+
+	diags := c.open(testURI, "let x = 1;\nlet = 2;\n")
+	if diags.URI != testURI || len(diags.Diagnostics) == 0 {
+		t.Fatalf("Expected diagnostics for %s, got %+v", testURI, diags)
+	}
+	if d := diags.Diagnostics[0]; d.Range.Start != (Position{Line: 1, Character: 4}) || d.Severity != SeverityError {
+		t.Fatalf("Unexpected diagnostic %+v", d)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x = 1;\n"}},
+	})
+	if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics after the fix, got %+v", diags)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, testDoc)
+
+	var symbols []DocumentSymbol
+	c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &symbols)
+
+	expected := []struct {
+		name   string
+		detail string
+		line   int
+	}{
+		{"a", "1", 0},
+		{"b", "a + 2", 1},
+		{"a", "a * b", 2},
+	}
+
+	if len(symbols) != len(expected) {
+		t.Fatalf("Expected %d symbols, got %+v", len(expected), symbols)
+	}
+	for i, exp := range expected {
+		s := symbols[i]
+		if s.Name != exp.name || s.Detail != exp.detail || s.SelectionRange.Start.Line != exp.line || s.Kind != SymbolKindVariable {
+			t.Fatalf("Symbol %d: expected %+v, got %+v", i, exp, s)
+		}
+	}
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, testDoc)
+
+	// a in the second let refers to the first one
+	var loc Location
+	c.call("textDocument/definition", positionParams(testURI, 1, 8), &loc)
+	if loc.Range.Start != (Position{Line: 0, Character: 4}) {
+		t.Fatalf("Expected definition at 0:4, got %+v", loc)
+	}
+
+	// a in the last line refers to the rebinding
+	c.call("textDocument/definition", positionParams(testURI, 3, 0), &loc)
+	if loc.Range.Start != (Position{Line: 2, Character: 4}) {
+		t.Fatalf("Expected definition at 2:4, got %+v", loc)
+	}
+
+	var refs []Location
+	params := ReferenceParams{TextDocumentPositionParams: positionParams(testURI, 0, 4)}
+	params.Context.IncludeDeclaration = true
+	c.call("textDocument/references", params, &refs)
+
+	expected := []Position{{Line: 0, Character: 4}, {Line: 1, Character: 8}, {Line: 2, Character: 8}}
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d references, got %+v", len(expected), refs)
+	}
+	for i, exp := range expected {
+		if refs[i].Range.Start != exp || refs[i].URI != testURI {
+			t.Fatalf("Reference %d: expected %+v, got %+v", i, exp, refs[i])
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, testDoc)
+
+	var h Hover
+	c.call("textDocument/hover", positionParams(testURI, 3, 4), &h)
+
+	const expected = "```monkey\nlet b = a + 2;\n```"
+	if h.Contents.Value != expected {
+		t.Fatalf("Expected hover %q, got %q", expected, h.Contents.Value)
+	}
+
+	var none *Hover
+	c.call("textDocument/hover", positionParams(testURI, 3, 2), &none)
+	if none != nil {
+		t.Fatalf("Expected no hover outside identifiers, got %+v", none)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, "let alpha = 1;\nlet beta = 2;\nlet another = 3;\na")
+
+	var items []CompletionItem
+	c.call("textDocument/completion", positionParams(testURI, 3, 1), &items)
+
+	expected := []string{"another", "alpha"}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, items)
+	}
+	for i, exp := range expected {
+		if items[i].Label != exp || items[i].Kind != CompletionKindVariable {
+			t.Fatalf("Item %d: expected %s, got %+v", i, exp, items[i])
+		}
+	}
+
+	c.call("textDocument/completion", positionParams(testURI, 1, 2), &items)
+	if len(items) != 1 || items[0].Label != "let" || items[0].Kind != CompletionKindKeyword {
+		t.Fatalf("Expected keyword let, got %+v", items)
+	}
+}
+
+func TestCompletionScopes(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, "let outer = 1;\nwhile (outer) { let inner = 2; i }\nfor (item in outer) {\n  let index = item;\n  i\n}\ni")
+
+	tests := []struct {
+		pos      Position
+		expected []string
+	}{
+		// after the while loop, its let is out of scope
+		{Position{Line: 6, Character: 1}, []string{"if", "in"}},
+		// inside the for loop, the loop variable and the let before
+		{Position{Line: 4, Character: 3}, []string{"index", "item", "if", "in"}},
+		// inside the while loop
+		{Position{Line: 1, Character: 32}, []string{"inner", "if", "in"}},
+	}
+
+	for _, tst := range tests {
+		var items []CompletionItem
+		c.call("textDocument/completion", positionParams(testURI, tst.pos.Line, tst.pos.Character), &items)
+
+		var got []string
+		for _, it := range items {
+			got = append(got, it.Label)
+		}
+		if strings.Join(got, " ") != strings.Join(tst.expected, " ") {
+			t.Fatalf("Completion at %+v: expected %v, got %v", tst.pos, tst.expected, got)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, "let a=1\n(a)+2")
+
+	var edits []TextEdit
+	c.call("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &edits)
+
+	if len(edits) != 1 {
+		t.Fatalf("Expected one edit, got %+v", edits)
+	}
+
+	e := edits[0]
+	if e.NewText != "let a = 1;\na + 2;\n" || e.Range.End != (Position{Line: 1, Character: 5}) {
+		t.Fatalf("Unexpected edit %+v", e)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+
+	c.send(&message{ID: json.RawMessage("100"), Method: "workspace/nope"})
+	msg, err := readMessage(c.fromServer)
+	if err != nil {
+		t.Fatalf("Reading from server failed: %v", err)
+	}
+
+	if msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Fatalf("Expected method not found error, got %+v", msg)
+	}
+}
+
+// 𝑥 is one character and two UTF-16 code units, é is one of both
+func TestNonASCIIPositions(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+
+	diags := c.open(testURI, "let 𝑥 = 1;\nlet été = 𝑥 + 𝑥;\n𝑥 + @;\nét")
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %+v", diags)
+	}
+	if r := diags.Diagnostics[0].Range; r.Start != (Position{Line: 2, Character: 5}) || r.End != (Position{Line: 2, Character: 6}) {
+		t.Fatalf("Unexpected diagnostic range %+v", r)
+	}
+
+	// the second 𝑥 on the line, after the first one took two units
+	var loc Location
+	c.call("textDocument/definition", positionParams(testURI, 1, 15), &loc)
+	if loc.Range != (Range{Start: Position{Line: 0, Character: 4}, End: Position{Line: 0, Character: 6}}) {
+		t.Fatalf("Expected definition at 0:4-0:6, got %+v", loc)
+	}
+
+	var refs []Location
+	c.call("textDocument/references", ReferenceParams{TextDocumentPositionParams: positionParams(testURI, 0, 5)}, &refs)
+	expected := []Position{{Line: 1, Character: 10}, {Line: 1, Character: 15}, {Line: 2, Character: 0}}
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d references, got %+v", len(expected), refs)
+	}
+	for i, exp := range expected {
+		if refs[i].Range.Start != exp {
+			t.Fatalf("Reference %d: expected %+v, got %+v", i, exp, refs[i])
+		}
+	}
+
+	var hover Hover
+	c.call("textDocument/hover", positionParams(testURI, 1, 5), &hover)
+	if hover.Range == nil || *hover.Range != (Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 7}}) {
+		t.Fatalf("Unexpected hover %+v", hover)
+	}
+
+	var items []CompletionItem
+	c.call("textDocument/completion", positionParams(testURI, 3, 2), &items)
+	if len(items) != 1 || items[0].Label != "été" {
+		t.Fatalf("Expected été, got %+v", items)
+	}
+}
+
+func TestBOMPositions(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+
+	// the client counts the BOM as the first character of the first line
+	diags := c.open("file:///bad.mk", "\uFEFFx + @;")
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %+v", diags)
+	}
+	if r := diags.Diagnostics[0].Range; r.Start != (Position{Line: 0, Character: 5}) || r.End != (Position{Line: 0, Character: 6}) {
+		t.Fatalf("Unexpected diagnostic range %+v", r)
+	}
+
+	c.open(testURI, "\uFEFFlet x = 1;\nlet y = x;")
+
+	var loc Location
+	c.call("textDocument/definition", positionParams(testURI, 1, 8), &loc)
+	if loc.Range != (Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 6}}) {
+		t.Fatalf("Expected definition at 0:5-0:6, got %+v", loc)
+	}
+
+	var hover Hover
+	c.call("textDocument/hover", positionParams(testURI, 0, 5), &hover)
+	if hover.Range == nil || *hover.Range != (Range{Start: Position{Line: 0, Character: 5}, End: Position{Line: 0, Character: 6}}) {
+		t.Fatalf("Unexpected hover %+v", hover)
+	}
+
+	// the BOM shifts the first line by one, the space is not the identifier
+	var none *Hover
+	c.call("textDocument/hover", positionParams(testURI, 0, 4), &none)
+	if none != nil {
+		t.Fatalf("Expected no hover on the space, got %+v", none)
+	}
+}
+
+func TestFormattingNonASCII(t *testing.T) {
+	c := newFakeClient(t)
+	defer c.close()
+	c.open(testURI, "let a=1\nlet 𝑥=a")
+
+	var edits []TextEdit
+	c.call("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &edits)
+
+	if len(edits) != 1 || edits[0].Range.End != (Position{Line: 1, Character: 8}) {
+		t.Fatalf("Unexpected edits %+v", edits)
+	}
+}
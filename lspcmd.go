@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/grzkv/m-interpreter/lsp"
+)
+
+// runLSP is *monkey lsp*. Serves the Language Server Protocol over stdio
+func runLSP(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "lsp: no arguments expected")
+		return 2
+	}
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
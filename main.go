@@ -1,11 +1,91 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"log"
 	"os"
 
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/parser"
 	"github.com/grzkv/m-interpreter/repl"
 )
 
+// commands take the arguments after the command name and return exit code
+var commands = map[string]func(args []string) int{
+	"fmt":   runFmt,
+	"ast":   runAST,
+	"lsp":   runLSP,
+	"vet":   runVet,
+	"check": runCheck,
+	"cat":   runCat,
+}
+
 func main() {
-	repl.RunREPL(os.Stdin, os.Stdout)
+	// parser debug logging would clutter the output of the tools
+	log.SetOutput(io.Discard)
+
+	if len(os.Args) < 2 {
+		repl.RunREPLColor(os.Stdin, os.Stdout, useColor(os.Stdout, false))
+		return
+	}
+
+	if os.Args[1] == "--no-color" {
+		repl.RunREPLColor(os.Stdin, os.Stdout, false)
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		os.Exit(2)
+	}
+
+	os.Exit(cmd(os.Args[2:]))
+}
+
+// readSource reads the named file. Empty name means stdin
+func readSource(name string) (string, error) {
+	if name == "" {
+		src, err := io.ReadAll(os.Stdin)
+		return string(src), err
+	}
+
+	src, err := os.ReadFile(name)
+	return string(src), err
+}
+
+// parseFile parses the named file as it is read. Empty name means stdin.
+// Parse errors are a parser.ErrorList
+func parseFile(name string) (*ast.Program, error) {
+	in := os.Stdin
+	if name != "" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	p := parser.NewFromReader(in)
+	prg := p.Parse()
+	if errs := p.ErrorList(); len(errs) != 0 {
+		return nil, errs
+	}
+
+	return prg, nil
+}
+
+// rereadSource reads the code of the file parsed by parseFile again.
+// Stdin can be read again only if it is a file, ok is false otherwise
+func rereadSource(name string) (src string, ok bool) {
+	if name == "" {
+		if _, err := os.Stdin.Seek(0, io.SeekStart); err != nil {
+			return "", false
+		}
+	}
+
+	src, err := readSource(name)
+	return src, err == nil
 }
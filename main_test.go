@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/highlight"
+)
+
+func TestPrintSource(t *testing.T) {
+	src := "let x = 5; // five\n"
+
+	tests := []struct {
+		asHTML, color bool
+		want          string
+	}{
+		{false, false, src},
+		{false, true, highlight.ANSI(src)},
+		{true, false, highlight.HTML(src)},
+		{true, true, highlight.HTML(src)},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		printSource(&b, src, tt.asHTML, tt.color)
+		if b.String() != tt.want {
+			t.Errorf("printSource(html %v, color %v) = %q, want %q", tt.asHTML, tt.color, b.String(), tt.want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv("NO_COLOR", "")
+	if useColor(f, false) {
+		t.Error("useColor is true for a regular file")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal:", err)
+	}
+	defer tty.Close()
+
+	if !useColor(tty, false) {
+		t.Error("useColor is false for a terminal")
+	}
+	if useColor(tty, true) {
+		t.Error("useColor is true for a terminal with --no-color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(tty, false) {
+		t.Error("useColor is true for a terminal with NO_COLOR set")
+	}
+}
@@ -1,12 +1,17 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"github.com/grzkv/m-interpreter/ast"
 	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/suggest"
 	"github.com/grzkv/m-interpreter/token"
+	"io"
 	"log"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 // Parser parses the code tokenized by lexer
@@ -15,49 +20,59 @@ type Parser struct {
 
 	current token.Token
 	peek    token.Token
-	errors  []string
+	errors  []Error
+
+	// number of loops around the current token
+	loopDepth int
 
 	prefixParseFns map[token.Typ]prefixParseFn
 	infixParseFns  map[token.Typ]infixParseFn
 }
 
+// operation precedence, see token.Typ.Precedence
 const (
-	// operation precedence
-	_ int = iota
 	// LOWEST is the default
-	LOWEST
+	LOWEST = token.LowestPrec
+	// ASSIGN is for = and the compound assignments. Right associative
+	ASSIGN = token.AssignPrec
+	// OR is ||
+	OR = token.OrPrec
+	// AND is &&
+	AND = token.AndPrec
 	// EQ is ==
-	EQ
-	// LESSGR is for > and <
-	LESSGR
+	EQ = token.EqPrec
+	// LESSGR is for >, <, >= and <=
+	LESSGR = token.LessGrPrec
+	// BITOR is for |
+	BITOR = token.BitOrPrec
+	// BITXOR is for ^
+	BITXOR = token.BitXorPrec
+	// BITAND is for &
+	BITAND = token.BitAndPrec
+	// SHIFT is for << and >>
+	SHIFT = token.ShiftPrec
 	// SUM is for +
-	SUM
-	// PRODUCT is for *
-	PRODUCT
+	SUM = token.SumPrec
+	// PRODUCT is for *, / and %
+	PRODUCT = token.ProductPrec
 	// PREFIX is for prefix oprators
-	PREFIX
+	PREFIX = token.PrefixPrec
+	// POWER is for **. Right associative, -a ** b is -(a ** b)
+	POWER = token.PowerPrec
 	// CALL is for function calls
-	CALL
+	CALL = token.CallPrec
+	// INDEX is for a[i]
+	INDEX = token.IndexPrec
 )
 
-var precedences = map[token.Typ]int{
-	token.EQ:      EQ,
-	token.NEQ:     EQ,
-	token.LESS:    LESSGR,
-	token.GREATER: LESSGR,
-	token.PLUS:    SUM,
-	token.MINUS:   SUM,
-	token.DIVIDE:  PRODUCT,
-	token.MULT:    PRODUCT,
-	token.LPAREN:  CALL,
+// Precedence returns the binding power of an infix operator token type.
+// Everything that is not an infix operator gets LOWEST
+func Precedence(typ token.Typ) int {
+	return typ.Precedence()
 }
 
 func getPrecedence(t token.Token) int {
-	if prec, ok := precedences[t.Typ]; ok {
-		return prec
-	}
-
-	return LOWEST
+	return Precedence(t.Typ)
 }
 
 type (
@@ -74,8 +89,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.Typ]prefixParseFn)
 	p.prefixParseFns[token.IDENT] = p.parseIdent
 	p.prefixParseFns[token.INT] = p.parseIntegerLiteral
+	p.prefixParseFns[token.FLOAT] = p.parseFloatLiteral
+	p.prefixParseFns[token.TRUE] = p.parseBooleanLiteral
+	p.prefixParseFns[token.FALSE] = p.parseBooleanLiteral
 	p.prefixParseFns[token.NOT] = p.parsePrefixExpr
 	p.prefixParseFns[token.MINUS] = p.parsePrefixExpr
+	p.prefixParseFns[token.BIT_NOT] = p.parsePrefixExpr
+	p.prefixParseFns[token.LPAREN] = p.parseGroupedExpr
+	p.prefixParseFns[token.ILLEGAL] = p.parseIllegal
 
 	p.infixParseFns = make(map[token.Typ]infixParseFn)
 	p.infixParseFns[token.PLUS] = p.parseInfixExpr
@@ -86,6 +107,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.infixParseFns[token.LESS] = p.parseInfixExpr
 	p.infixParseFns[token.EQ] = p.parseInfixExpr
 	p.infixParseFns[token.NEQ] = p.parseInfixExpr
+	p.infixParseFns[token.LESS_EQ] = p.parseInfixExpr
+	p.infixParseFns[token.GREATER_EQ] = p.parseInfixExpr
+	p.infixParseFns[token.MOD] = p.parseInfixExpr
+	p.infixParseFns[token.POWER] = p.parseInfixExpr
+	p.infixParseFns[token.BIT_AND] = p.parseInfixExpr
+	p.infixParseFns[token.BIT_OR] = p.parseInfixExpr
+	p.infixParseFns[token.BIT_XOR] = p.parseInfixExpr
+	p.infixParseFns[token.SHIFT_LEFT] = p.parseInfixExpr
+	p.infixParseFns[token.SHIFT_RIGHT] = p.parseInfixExpr
+	p.infixParseFns[token.AND] = p.parseInfixExpr
+	p.infixParseFns[token.OR] = p.parseInfixExpr
+	p.infixParseFns[token.LBRACKET] = p.parseIndexExpr
+	p.infixParseFns[token.ASSIGN] = p.parseAssignExpr
+	p.infixParseFns[token.PLUS_ASSIGN] = p.parseAssignExpr
+	p.infixParseFns[token.MINUS_ASSIGN] = p.parseAssignExpr
+	p.infixParseFns[token.MULT_ASSIGN] = p.parseAssignExpr
+	p.infixParseFns[token.DIVIDE_ASSIGN] = p.parseAssignExpr
 
 	p.nextToken()
 	p.nextToken()
@@ -93,53 +131,208 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// NewFromReader makes a parser of the code read as parsing goes,
+// see lexer.NewReader. Errors reading the code are parse errors
+func NewFromReader(r io.Reader) *Parser {
+	return New(lexer.NewReader(r))
+}
+
 func (p *Parser) nextToken() {
 	log.Println("Moving to next token")
 
 	p.current = p.peek
 	p.peek = p.l.NextToken()
+
+	// comments are of no interest to the parser
+	for p.peek.Typ == token.COMMENT {
+		p.peek = p.l.NextToken()
+	}
+}
+
+// Error is a parsing error at a position in the code
+type Error struct {
+	Pos token.Pos
+	Msg string
+	// Labels point at the related places, e.g. where the statement
+	// with the error started
+	Labels []Label
+
+	// lexical errors are about the text the lexer made no token of
+	// and read as the lexer ones
+	lexical bool
+}
+
+// Label is a place in the code related to an error
+type Label struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e Error) Error() string {
+	if e.lexical {
+		return e.Msg + " at " + e.Pos.String()
+	}
+
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorList is the errors of parsing as one error, one per line
+type ErrorList []Error
+
+func (l ErrorList) Error() string {
+	lines := make([]string, len(l))
+	for i, e := range l {
+		lines[i] = e.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Errors returns the errors found during parsing as text
+func (p *Parser) Errors() []string {
+	errs := make([]string, len(p.errors))
+	for i, e := range p.errors {
+		errs[i] = e.Error()
+	}
+
+	return errs
+}
+
+// ErrorList returns the errors found during parsing with their positions
+func (p *Parser) ErrorList() ErrorList {
+	return p.errors
+}
+
+func (p *Parser) addError(pos token.Pos, format string, args ...interface{}) {
+	p.errors = append(p.errors, Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// labelErrors adds the label to the errors found since the first one.
+// The errors at the labeled position and the errors that have a label
+// of a nested statement already are left as they are
+func (p *Parser) labelErrors(first int, pos token.Pos, msg string) {
+	for i := first; i < len(p.errors); i++ {
+		if e := &p.errors[i]; e.Pos != pos && len(e.Labels) == 0 {
+			e.Labels = append(e.Labels, Label{Pos: pos, Msg: msg})
+		}
+	}
 }
 
 // Parse the loaded code
 func (p *Parser) Parse() *ast.Program {
 	prg := ast.Program{}
 
-	for p.current.Typ != token.EOF {
+	prg.StNodes = p.parseStatements(token.EOF)
+
+	if err := p.l.Err(); err != nil {
+		p.addError(p.current.Pos, "reading code: %v", err)
+	}
+
+	return &prg
+}
+
+// parseStatements parses statements until the token of the end type
+// or the end of the code
+func (p *Parser) parseStatements(end token.Typ) []ast.Node {
+	var sts []ast.Node
+
+	for p.current.Typ != end && p.current.Typ != token.EOF {
+		start := p.current
 		st := p.parseStatement()
 
 		if st != nil {
-			prg.StNodes = append(prg.StNodes, st)
+			sts = append(sts, st)
 		} else {
 			log.Println("error: got nil statement during parsing")
+
+			p.skipStatement()
+
+			// a statement that failed at its first token
+			// would be parsed again and again
+			if p.current == start {
+				p.nextToken()
+			}
 		}
 	}
 
-	p.printErrorsIfAny()
-
-	return &prg
+	return sts
 }
 
-func (p *Parser) printErrorsIfAny() {
-	if len(p.errors) == 0 {
-		return
-	}
+// skipStatement moves past the rest of a statement that failed to parse,
+// so that its leftovers are not parsed as statements and reported again.
+// Stops after the next semicolon or the block that closes at the same
+// nesting level, or at the closing brace of the enclosing block
+func (p *Parser) skipStatement() {
+	depth := 0
 
-	fmt.Printf("PARSER GOT %d ERRORS:\n", len(p.errors))
-	for _, e := range p.errors {
-		fmt.Println(e)
+	for p.current.Typ != token.EOF {
+		switch p.current.Typ {
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		}
+		p.nextToken()
 	}
 }
 
+// statementNames name the statements in the error labels
+var statementNames = map[token.Typ]string{
+	token.LET:    "let",
+	token.RETURN: "return",
+	token.WHILE:  "while",
+	token.FOR:    "for",
+}
+
 func (p *Parser) parseStatement() ast.StNode {
 	log.Println("Parsing a stament")
 
+	if name, ok := statementNames[p.current.Typ]; ok {
+		defer p.labelErrors(len(p.errors), p.current.Pos, name+" statement started here")
+	}
+
+	// nil pointers are converted to nil interfaces explicitly,
+	// so that Parse can tell a failed statement
 	switch p.current.Typ {
 	case token.LET:
-		return p.parseLetSt()
+		if st := p.parseLetSt(); st != nil {
+			return st
+		}
+		return nil
 	case token.RETURN:
-		return p.parseReturnSt()
+		if st := p.parseReturnSt(); st != nil {
+			return st
+		}
+		return nil
+	case token.WHILE:
+		if st := p.parseWhileSt(); st != nil {
+			return st
+		}
+		return nil
+	case token.FOR:
+		if st := p.parseForSt(); st != nil {
+			return st
+		}
+		return nil
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlSt()
 	default:
-		return p.parseExpressionSt()
+		if st := p.parseExpressionSt(); st != nil {
+			return st
+		}
+		return nil
 	}
 }
 
@@ -147,6 +340,11 @@ func (p *Parser) parseExpressionSt() *ast.ExpressionSt {
 	st := ast.ExpressionSt{RootToken: p.current}
 
 	st.Expr = p.parseExpr(LOWEST)
+	if st.Expr == nil {
+		return nil
+	}
+
+	p.checkMisspelledKeyword(st.Expr)
 
 	if p.peek.Typ == token.SEMICOLON {
 		p.nextToken()
@@ -157,16 +355,37 @@ func (p *Parser) parseExpressionSt() *ast.ExpressionSt {
 	return &st
 }
 
+// checkMisspelledKeyword reports a statement that is a lone identifier
+// followed by more code on its line, like retrun x, if the identifier
+// looks like a keyword
+func (p *Parser) checkMisspelledKeyword(e ast.ExprNode) {
+	id, ok := e.(*ast.IdentifierEx)
+	if !ok || p.peek.Pos.Line != id.Token.Pos.Line {
+		return
+	}
+
+	switch p.peek.Typ {
+	case token.SEMICOLON, token.RBRACE, token.EOF:
+		return
+	}
+
+	if kw := suggest.Closest(id.Value, token.Keywords()); kw != "" {
+		p.addError(id.Token.Pos, "%s is not a keyword%s", id.Value, suggest.Hint(kw))
+	}
+}
+
 func (p *Parser) parseExpr(prio int) ast.ExprNode {
 	prefixFn := p.prefixParseFns[p.current.Typ]
 
 	if prefixFn == nil {
+		p.addError(p.current.Pos, "no prefix parse function for %s", p.current.Typ)
 		return nil
 	}
 
 	left := prefixFn()
 
-	for !(p.peek.Typ == token.SEMICOLON) && prio < getPrecedence(p.peek) {
+	// an operand that failed to parse ends the expression
+	for left != nil && p.peek.Typ != token.SEMICOLON && prio < getPrecedence(p.peek) {
 		infix := p.infixParseFns[p.peek.Typ]
 		if infix == nil {
 			return left
@@ -187,7 +406,7 @@ func (p *Parser) parseIdent() ast.ExprNode {
 func (p *Parser) parseLetSt() *ast.LetSt {
 	if p.current.Typ != token.LET {
 		log.Println("error: wrong token type")
-		p.errors = append(p.errors, "wrong token type")
+		p.addError(p.current.Pos, "wrong token type")
 		return nil
 	}
 
@@ -197,16 +416,26 @@ func (p *Parser) parseLetSt() *ast.LetSt {
 
 	if p.current.Typ != token.IDENT {
 		log.Println("error: wrong token type")
-		p.errors = append(p.errors, "wrong token type")
+		p.addError(p.current.Pos, "wrong token type")
 		return nil
 	}
 
 	st.Ident = &ast.IdentifierEx{Token: p.current, Value: p.current.Literal}
 
 	p.nextToken()
+	if p.current.Typ == token.COLON {
+		p.nextToken()
+
+		st.Type = p.parseType()
+		if st.Type == nil {
+			return nil
+		}
+		p.nextToken()
+	}
+
 	if p.current.Typ != token.ASSIGN {
 		log.Println("error: wrong token type")
-		p.errors = append(p.errors, "wrong token type")
+		p.addError(p.current.Pos, "wrong token type")
 		return nil
 	}
 
@@ -215,44 +444,238 @@ func (p *Parser) parseLetSt() *ast.LetSt {
 
 	if p.current.Typ == token.SEMICOLON {
 		log.Println("error: empty expression in let statement")
-		p.errors = append(p.errors, "wrong token type")
+		p.addError(p.current.Pos, "wrong token type")
 		return nil
 	}
 
-	for p.current.Typ != token.SEMICOLON {
-		p.nextToken()
+	st.Expr = p.parseExpr(LOWEST)
+	if st.Expr == nil {
+		return nil
 	}
-	p.nextToken() // pass the semicolon
 
-	st.Expr = nil
+	p.checkMisspelledKeyword(st.Expr)
+
+	if p.peek.Typ == token.SEMICOLON {
+		p.nextToken()
+	}
+	p.nextToken()
 
 	return &st
 }
 
 func (p *Parser) parseReturnSt() *ast.ReturnSt {
 	if p.current.Typ != token.RETURN {
-		p.errors = append(p.errors, fmt.Sprintf("Got wrong token type %s for return statement", p.current.Typ))
+		p.addError(p.current.Pos, "Got wrong token type %s for return statement", p.current.Typ)
 		log.Printf("error: got wrong token type for return statement")
 	}
 
-	returnSt := ast.ReturnSt{RootToken: p.current, Expr: nil}
+	returnSt := ast.ReturnSt{RootToken: p.current}
 
-	for p.current.Typ != token.SEMICOLON {
-		p.nextToken()
+	p.nextToken()
+
+	returnSt.Expr = p.parseExpr(LOWEST)
+	if returnSt.Expr == nil {
+		return nil
 	}
 
-	p.nextToken() // pass semicolon
+	if p.peek.Typ == token.SEMICOLON {
+		p.nextToken()
+	}
+	p.nextToken()
 
 	return &returnSt
 }
 
+// parseBlockSt parses statements in braces. Stops after the closing brace
+func (p *Parser) parseBlockSt() *ast.BlockSt {
+	block := &ast.BlockSt{Token: p.current}
+
+	p.nextToken()
+	block.StNodes = p.parseStatements(token.RBRACE)
+
+	if p.current.Typ != token.RBRACE {
+		p.addError(p.current.Pos, "expected %s, got %s", token.RBRACE, p.current.Typ)
+		return nil
+	}
+	block.Rbrace = p.current
+	p.nextToken()
+
+	return block
+}
+
+// parseLoopBody parses the block of a loop. A semicolon after it is allowed
+func (p *Parser) parseLoopBody() *ast.BlockSt {
+	p.loopDepth++
+	body := p.parseBlockSt()
+	p.loopDepth--
+
+	if body != nil && p.current.Typ == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return body
+}
+
+func (p *Parser) parseWhileSt() *ast.WhileSt {
+	st := &ast.WhileSt{Token: p.current}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	st.Cond = p.parseExpr(LOWEST)
+	if st.Cond == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	st.Body = p.parseLoopBody()
+	if st.Body == nil {
+		return nil
+	}
+
+	return st
+}
+
+func (p *Parser) parseForSt() *ast.ForSt {
+	st := &ast.ForSt{Token: p.current}
+
+	if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	st.Ident = &ast.IdentifierEx{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	st.Collection = p.parseExpr(LOWEST)
+	if st.Collection == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	st.Body = p.parseLoopBody()
+	if st.Body == nil {
+		return nil
+	}
+
+	return st
+}
+
+// parseLoopControlSt parses break and continue
+func (p *Parser) parseLoopControlSt() ast.StNode {
+	tok := p.current
+	if p.loopDepth == 0 {
+		p.addError(tok.Pos, "%s outside loop", tok.Literal)
+	}
+
+	if p.peek.Typ == token.SEMICOLON {
+		p.nextToken()
+	}
+	p.nextToken()
+
+	if tok.Typ == token.BREAK {
+		return &ast.BreakSt{Token: tok}
+	}
+	return &ast.ContinueSt{Token: tok}
+}
+
+// expectPeek moves to the next token if it is of the type.
+// Otherwise records an error
+func (p *Parser) expectPeek(typ token.Typ) bool {
+	if p.peek.Typ != typ {
+		p.addError(p.peek.Pos, "expected %s, got %s", typ, p.peek.Typ)
+		return false
+	}
+
+	p.nextToken()
+	return true
+}
+
+// parseType parses a type annotation starting at the current token.
+// Stops at the last token of the type
+func (p *Parser) parseType() *ast.TypeExpr {
+	t := &ast.TypeExpr{Token: p.current}
+
+	switch p.current.Typ {
+	case token.IDENT:
+		t.Name = p.current.Literal
+	case token.LBRACKET:
+		p.nextToken()
+		elem := p.parseType()
+		if elem == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		t.Args = []*ast.TypeExpr{elem}
+	case token.LBRACE:
+		p.nextToken()
+		key := p.parseType()
+		if key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseType()
+		if value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		t.Args = []*ast.TypeExpr{key, value}
+	case token.FUNCTION:
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for p.peek.Typ != token.RPAREN {
+			if len(t.Args) != 0 && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			t.Args = append(t.Args, param)
+		}
+		p.nextToken()
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		result := p.parseType()
+		if result == nil {
+			return nil
+		}
+		t.Args = append(t.Args, result)
+	default:
+		p.addError(p.current.Pos, "expected type, got %s", p.current.Typ)
+		return nil
+	}
+
+	return t
+}
+
+// parseIllegal reports the text the lexer could not make a token of
+func (p *Parser) parseIllegal() ast.ExprNode {
+	e := lexer.IllegalError(p.current)
+	p.errors = append(p.errors, Error{Pos: e.Pos, Msg: e.Msg, lexical: true})
+
+	return nil
+}
+
 func (p *Parser) parseIntegerLiteral() ast.ExprNode {
 	intLitExpr := ast.IntegerLiteralEx{Token: p.current}
 
 	val, err := strconv.ParseInt(p.current.Literal, 0, 64)
 
+	if errors.Is(err, strconv.ErrRange) {
+		// too big for int64, the value is exact anyway
+		if b, ok := new(big.Int).SetString(p.current.Literal, 0); ok {
+			intLitExpr.Big = b
+			return &intLitExpr
+		}
+	}
+
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("Error while parsing integer literal: %v", err))
+		p.addError(p.current.Pos, "malformed integer literal %s", p.current.Literal)
 		log.Println("error parsing integer literal")
 
 		return nil
@@ -263,6 +686,31 @@ func (p *Parser) parseIntegerLiteral() ast.ExprNode {
 	return &intLitExpr
 }
 
+func (p *Parser) parseFloatLiteral() ast.ExprNode {
+	lit := p.current.Literal
+
+	if strings.HasPrefix(lit, ".") {
+		p.addError(p.current.Pos, "malformed float literal %s: need a digit before the point, e.g. 0%s", lit, lit)
+		return nil
+	}
+
+	val, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			p.addError(p.current.Pos, "float literal %s is out of range", lit)
+		} else {
+			p.addError(p.current.Pos, "malformed float literal %s", lit)
+		}
+		return nil
+	}
+
+	return &ast.FloatLiteral{Token: p.current, Value: val}
+}
+
+func (p *Parser) parseBooleanLiteral() ast.ExprNode {
+	return &ast.BooleanLiteral{Token: p.current, Value: p.current.Typ == token.TRUE}
+}
+
 func (p *Parser) parsePrefixExpr() ast.ExprNode {
 	prefixExpr := ast.PrefixExpr{
 		Token: p.current,
@@ -276,17 +724,78 @@ func (p *Parser) parsePrefixExpr() ast.ExprNode {
 	return &prefixExpr
 }
 
+func (p *Parser) parseGroupedExpr() ast.ExprNode {
+	p.nextToken()
+
+	expr := p.parseExpr(LOWEST)
+
+	if p.peek.Typ != token.RPAREN {
+		p.addError(p.peek.Pos, "expected %s, got %s", token.RPAREN, p.peek.Typ)
+		return nil
+	}
+	p.nextToken()
+
+	return expr
+}
+
 func (p *Parser) parseInfixExpr(left ast.ExprNode) ast.ExprNode {
 	expr := &ast.InfixExpr{
 		OpToken: p.current,
-		Op: p.current.Literal,
-		Left: left,
+		Op:      p.current.Literal,
+		Left:    left,
 	}
 
 	precdence := getPrecedence(p.current)
+	if p.current.Typ == token.POWER {
+		// right associative: a ** b ** c is a ** (b ** c)
+		precdence--
+	}
 	p.nextToken()
 
 	expr.Right = p.parseExpr(precdence)
 
 	return expr
 }
+
+func (p *Parser) parseIndexExpr(left ast.ExprNode) ast.ExprNode {
+	expr := &ast.IndexExpr{Token: p.current, Left: left}
+
+	p.nextToken()
+	expr.Index = p.parseExpr(LOWEST)
+
+	if expr.Index == nil || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return expr
+}
+
+// parseAssignExpr parses assignments. Only names and index
+// expressions can be assigned to
+func (p *Parser) parseAssignExpr(target ast.ExprNode) ast.ExprNode {
+	expr := &ast.AssignExpr{
+		Token:  p.current,
+		Target: target,
+		Op:     p.current.Literal,
+	}
+
+	switch target.(type) {
+	case *ast.IdentifierEx, *ast.IndexExpr:
+	case nil:
+		// the error is reported already
+		return nil
+	default:
+		p.addError(p.current.Pos, "cannot assign to %s", target)
+		return nil
+	}
+
+	p.nextToken()
+
+	// right associative: a = b = c is a = (b = c)
+	expr.Value = p.parseExpr(ASSIGN - 1)
+	if expr.Value == nil {
+		return nil
+	}
+
+	return expr
+}
@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/grzkv/m-interpreter/ast"
 	"github.com/grzkv/m-interpreter/lexer"
@@ -319,6 +322,66 @@ func TestExpressionParsingWithOpPrecedence(t *testing.T) {
 			"a == b * c",
 			"(a == (b * c))\n",
 		},
+		{
+			"(a + b) * c",
+			"((a + b) * c)\n",
+		},
+		{
+			"-(a + b)",
+			"(-(a + b))\n",
+		},
+		{
+			"let x = a + b * c;",
+			"let x = (a + (b * c));\n",
+		},
+		{
+			"return -a;",
+			"return (-a)\n",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))\n",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))\n",
+		},
+		{
+			"a < b && b == c || !d",
+			"(((a < b) && (b == c)) || (!d))\n",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))\n",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))\n",
+		},
+		{
+			"a ** b ** -c * d",
+			"((a ** (b ** (-c))) * d)\n",
+		},
+		{
+			"a % b + c <= d >= e",
+			"((((a % b) + c) <= d) >= e)\n",
+		},
+		{
+			"a | b ^ c & d << e + f",
+			"(a | (b ^ (c & (d << (e + f)))))\n",
+		},
+		{
+			"~a >> b == c | d",
+			"(((~a) >> b) == (c | d))\n",
+		},
+		{
+			"let x: int = 1;",
+			"let x: int = 1;\n",
+		},
+		{
+			"let f: fn([int], {string: bool}) -> fn() -> int = g;",
+			"let f: fn([int], {string: bool}) -> fn() -> int = g;\n",
+		},
 	}
 
 	for _, tst := range tests {
@@ -335,3 +398,454 @@ func TestExpressionParsingWithOpPrecedence(t *testing.T) {
 		}
 	}
 }
+
+func TestIntegerBases(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_dead_BEEF", 0xdeadbeef},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", tst.in, p.Errors())
+		}
+
+		lit := prg.StNodes[0].(*ast.ExpressionSt).Expr.(*ast.IntegerLiteralEx)
+		if lit.Value != tst.expected || lit.String() != tst.in {
+			t.Fatalf("Expected %d spelled %q, got %d spelled %q", tst.expected, tst.in, lit.Value, lit.String())
+		}
+	}
+
+	p := New(lexer.New("0x1_0000_0000_0000_0000"))
+	lit := p.Parse().StNodes[0].(*ast.ExpressionSt).Expr.(*ast.IntegerLiteralEx)
+	if lit.Big == nil || lit.Big.Text(16) != "10000000000000000" {
+		t.Fatalf("Expected big value 0x10000000000000000, got %v", lit.Big)
+	}
+
+	errTests := []struct {
+		in       string
+		expected string
+	}{
+		{"0x;", "1:1: malformed integer literal 0x"},
+		{"a + 1__0;", "1:5: malformed integer literal 1__0"},
+		{"0b12;", "1:1: malformed integer literal 0b12"},
+		{"1_;", "1:1: malformed integer literal 1_"},
+		{"0xfg;", "1:1: malformed integer literal 0xfg"},
+		{"1__0.5;", "1:1: malformed float literal 1__0.5"},
+	}
+
+	for _, tst := range errTests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tst.expected {
+			t.Fatalf("Parsing %q: expected error %q, got %v", tst.in, tst.expected, p.Errors())
+		}
+	}
+}
+
+func TestBigIntegerLiteral(t *testing.T) {
+	tests := []string{
+		"9223372036854775808",
+		"123456789012345678901234567890",
+	}
+
+	for _, in := range tests {
+		p := New(lexer.New(in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", in, p.Errors())
+		}
+
+		lit := prg.StNodes[0].(*ast.ExpressionSt).Expr.(*ast.IntegerLiteralEx)
+		if lit.Big == nil || lit.Big.String() != in || lit.String() != in {
+			t.Fatalf("Expected big value %s, got %v", in, lit.Big)
+		}
+	}
+
+	p := New(lexer.New("9223372036854775807"))
+	lit := p.Parse().StNodes[0].(*ast.ExpressionSt).Expr.(*ast.IntegerLiteralEx)
+	if lit.Big != nil || lit.Value != 9223372036854775807 {
+		t.Fatalf("Expected int64 value, got %d and %v", lit.Value, lit.Big)
+	}
+}
+
+func TestFloatLiteral(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"2.5E+3", 2500},
+		{"0.0", 0},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", tst.in, p.Errors())
+		}
+
+		lit, ok := prg.StNodes[0].(*ast.ExpressionSt).Expr.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("Expected float literal for %q, got %s", tst.in, prg)
+		}
+		if lit.Value != tst.expected || lit.String() != tst.in {
+			t.Fatalf("Expected %v spelled %q, got %v spelled %q", tst.expected, tst.in, lit.Value, lit.String())
+		}
+	}
+
+	errTests := []struct {
+		in       string
+		expected string
+	}{
+		{"x = .5;", "1:5: malformed float literal .5: need a digit before the point, e.g. 0.5"},
+		{"1e + 2;", "1:1: malformed float literal 1e"},
+		{"2.5e-;", "1:1: malformed float literal 2.5e-"},
+		{"1e400;", "1:1: float literal 1e400 is out of range"},
+	}
+
+	for _, tst := range errTests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tst.expected {
+			t.Fatalf("Parsing %q: expected error %q, got %v", tst.in, tst.expected, p.Errors())
+		}
+	}
+}
+
+func TestBooleanLiteral(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"true;", "true\n"},
+		{"!false == true;", "((!false) == true)\n"},
+		{"let b = 1 < 2 && false;", "let b = ((1 < 2) && false);\n"},
+		{"x = true || y;", "(x = (true || y))\n"},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", tst.in, p.Errors())
+		}
+		if prg.String() != tst.expected {
+			t.Fatalf("Got %q, expected %q", prg.String(), tst.expected)
+		}
+	}
+
+	for in, expected := range map[string]bool{"true": true, "false": false} {
+		lit, ok := New(lexer.New(in)).Parse().StNodes[0].(*ast.ExpressionSt).Expr.(*ast.BooleanLiteral)
+		if !ok || lit.Value != expected {
+			t.Fatalf("Expected boolean literal %v for %q", expected, in)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	p := New(lexer.New("let a = 1;\nlet b = \xff\xfe;"))
+	p.Parse()
+
+	if len(p.Errors()) == 0 || p.Errors()[0] != "invalid UTF-8 encoding at 2:9" {
+		t.Fatalf("Expected invalid UTF-8 error, got %v", p.Errors())
+	}
+}
+
+func TestIllegalCharacters(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []string
+	}{
+		{"let a = 1;\n\nlet b = a @ 2;", []string{"unexpected character '@' at 3:11"}},
+		{"$$ + 1; x;", []string{"unexpected characters '$$' at 1:1"}},
+		{"let s = 1.5.;", []string{"unexpected character '.' at 1:12"}},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		if strings.Join(p.Errors(), "\n") != strings.Join(tst.expected, "\n") {
+			t.Fatalf("Parsing %q: expected errors %v, got %v", tst.in, tst.expected, p.Errors())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"let x = ;",
+		"(a + b",
+		"let = 5;",
+		"return ;",
+		"let x: = 1;",
+		"let x: [int = 1;",
+		"let x: {int} = 1;",
+		"let x: fn(int int) -> int = 1;",
+		"let x: fn(int) = 1;",
+	}
+
+	for _, in := range tests {
+		p := New(lexer.New(in))
+		prg := p.Parse()
+
+		if len(p.Errors()) == 0 {
+			t.Fatalf("Expected errors parsing %q", in)
+		}
+
+		for _, st := range prg.StNodes {
+			if st == nil {
+				t.Fatalf("Got nil statement parsing %q", in)
+			}
+		}
+	}
+}
+
+func TestMisspelledKeywords(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"retrun x;", "1:1: retrun is not a keyword, did you mean return?"},
+		{"while (a) {\n  brek a\n}", "2:3: brek is not a keyword, did you mean break?"},
+		{"lte x = 1;", "1:1: lte is not a keyword, did you mean let?"},
+		{"retrun;", ""},
+		{"retrun\nx;", ""},
+		{"while (a) { contine }", ""},
+		{"value x;", ""},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		got := strings.Join(p.Errors(), "\n")
+		if got != tst.expected {
+			t.Fatalf("Parsing %q: expected errors %q, got %q", tst.in, tst.expected, got)
+		}
+	}
+}
+
+func TestErrorLabels(t *testing.T) {
+	tests := []struct {
+		in     string
+		labels []Label
+	}{
+		{"let x = ;", []Label{{Pos: token.Pos{Line: 1, Col: 1}, Msg: "let statement started here"}}},
+		{"a;\n  return a +;", []Label{{Pos: token.Pos{Line: 2, Col: 3}, Msg: "return statement started here"}}},
+		{"while (a) {\n  let b = ;\n}", []Label{{Pos: token.Pos{Line: 2, Col: 3}, Msg: "let statement started here"}}},
+		{"for (x in xs) {", []Label{{Pos: token.Pos{Line: 1, Col: 1}, Msg: "for statement started here"}}},
+		{"let = 5;", []Label{{Pos: token.Pos{Line: 1, Col: 1}, Msg: "let statement started here"}}},
+		{"x = ;", nil},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		errs := p.ErrorList()
+		if len(errs) == 0 {
+			t.Fatalf("Expected errors parsing %q", tst.in)
+		}
+
+		if !reflect.DeepEqual(errs[0].Labels, tst.labels) {
+			t.Fatalf("Parsing %q: expected labels %v, got %v", tst.in, tst.labels, errs[0].Labels)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	p := New(lexer.New("let = 5;"))
+	p.Parse()
+
+	if got, exp := p.ErrorList().Error(), strings.Join(p.Errors(), "\n"); got != exp {
+		t.Fatalf("Expected %q, got %q", exp, got)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []string
+	}{
+		{"let x = ;", []string{"1:9: wrong token type"}},
+		{"let = 5; a;", []string{"1:5: wrong token type"}},
+		{".5;", []string{"1:1: malformed float literal .5: need a digit before the point, e.g. 0.5"}},
+		{"1__0;", []string{"1:1: malformed integer literal 1__0"}},
+		{"(a;", []string{"1:3: expected ), got ;"}},
+		{"@;", []string{"unexpected character '@' at 1:1"}},
+		{"while (a { }", []string{"1:10: expected ), got {"}},
+		{"while (a { b; } c;", []string{"1:10: expected ), got {"}},
+		{"let a = ;\nlet b = );\nc;", []string{"1:9: wrong token type", "2:9: no prefix parse function for )"}},
+		{"while (a) {\n  let x = ;\n  x = @;\n}\nb;", []string{"2:11: wrong token type", "unexpected character '@' at 3:7"}},
+		{"while (a) { let x = }", []string{"1:21: no prefix parse function for }"}},
+		{"}", []string{"1:1: no prefix parse function for }"}},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if strings.Join(p.Errors(), "\n") != strings.Join(tst.expected, "\n") {
+			t.Fatalf("Parsing %q: expected errors %q, got %q", tst.in, tst.expected, p.Errors())
+		}
+
+		for _, st := range prg.StNodes {
+			if st == nil {
+				t.Fatalf("Got nil statement parsing %q", tst.in)
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{
+			"while (a < b) { a; };",
+			"while (a < b) { a }\n",
+		},
+		{
+			"for (x in xs) { if_x; continue; while (x) { break; } }",
+			"for (x in xs) { if_x continue; while x { break; } }\n",
+		},
+		{
+			"while (a) {} b;",
+			"while a { }\nb\n",
+		},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", tst.in, p.Errors())
+		}
+
+		if prg.String() != tst.expected {
+			t.Fatalf("Got %q, expected %q", prg.String(), tst.expected)
+		}
+	}
+
+	p := New(lexer.New("for (x in xs) { x; }"))
+	forSt, ok := p.Parse().StNodes[0].(*ast.ForSt)
+	if !ok {
+		t.Fatal("Expected for statement")
+	}
+	if forSt.Ident.Value != "x" || forSt.Body.Rbrace.Typ != token.RBRACE || len(forSt.Body.StNodes) != 1 {
+		t.Fatalf("Unexpected for statement %s", forSt)
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"while (a) {} continue", "1:14: continue outside loop"},
+		{"while a {}", "1:7: expected (, got IDENT"},
+		{"for (x xs) {}", "1:8: expected IN, got IDENT"},
+		{"for (1 in xs) {}", "1:6: expected IDENT, got INT"},
+		{"while (a) { a;", "1:15: expected }, got EOF"},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tst.expected {
+			t.Fatalf("Parsing %q: expected error %q, got %v", tst.in, tst.expected, p.Errors())
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"x = 1;", "(x = 1)\n"},
+		{"x = y = a + b;", "(x = (y = (a + b)))\n"},
+		{"x += a * b;", "(x += (a * b))\n"},
+		{"a[i + 1] -= 2;", "((a[(i + 1)]) -= 2)\n"},
+		{"a[i][j] /= -b[0];", "(((a[i])[j]) /= (-(b[0])))\n"},
+		{"x *= y == z;", "(x *= (y == z))\n"},
+		{"while (x) { x = x - 1; }", "while x { (x = (x - 1)) }\n"},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		prg := p.Parse()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("Parser got errors for %q: %v", tst.in, p.Errors())
+		}
+
+		if prg.String() != tst.expected {
+			t.Fatalf("Got %q, expected %q", prg.String(), tst.expected)
+		}
+	}
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"1 = x;", "1:3: cannot assign to 1"},
+		{"a + b = c;", "1:7: cannot assign to (a + b)"},
+		{"x = ;", "1:5: no prefix parse function for ;"},
+		{"a[1 = 2;", "1:5: cannot assign to 1"},
+		{"x[] = 1;", "1:3: no prefix parse function for ]"},
+		{"x[] += y[];", "1:3: no prefix parse function for ]"},
+	}
+
+	for _, tst := range tests {
+		p := New(lexer.New(tst.in))
+		p.Parse()
+
+		if len(p.Errors()) != 1 || p.Errors()[0] != tst.expected {
+			t.Fatalf("Parsing %q: expected error %q, got %v", tst.in, tst.expected, p.Errors())
+		}
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	src := "let a: int = 1;\nwhile (a < 10) { a += 1; }\nreturn a ** 2;"
+
+	p := NewFromReader(iotest.OneByteReader(strings.NewReader(src)))
+	prg := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser got errors: %v", p.Errors())
+	}
+
+	if exp := New(lexer.New(src)).Parse().String(); prg.String() != exp {
+		t.Fatalf("Expected %q, got %q", exp, prg.String())
+	}
+
+	p = NewFromReader(iotest.TimeoutReader(iotest.HalfReader(strings.NewReader("let a = 10;"))))
+	p.Parse()
+	if errs := p.Errors(); len(errs) == 0 || !strings.HasSuffix(errs[len(errs)-1], "reading code: timeout") {
+		t.Fatalf("Expected reading error, got %v", errs)
+	}
+}
@@ -5,12 +5,20 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/grzkv/m-interpreter/diag"
+	"github.com/grzkv/m-interpreter/highlight"
 	"github.com/grzkv/m-interpreter/lexer"
-	"github.com/grzkv/m-interpreter/token"
+	"github.com/grzkv/m-interpreter/parser"
 )
 
-// RunREPL runs Monkey REPL
+// RunREPL runs Monkey REPL without colors
 func RunREPL(r io.Reader, w io.Writer) {
+	RunREPLColor(r, w, false)
+}
+
+// RunREPLColor runs Monkey REPL. With color set input lines are echoed
+// highlighted and the parse errors are colored
+func RunREPLColor(r io.Reader, w io.Writer, color bool) {
 	var PROMPT = "> "
 
 	scnr := bufio.NewScanner(r)
@@ -21,16 +29,32 @@ func RunREPL(r io.Reader, w io.Writer) {
 		if scnr.Scan() {
 			line := scnr.Text()
 
-			l := lexer.New(line)
+			if color {
+				fmt.Fprintln(w, highlight.ANSI(line))
+			}
+
+			p := parser.New(lexer.New(line))
+			p.Parse()
+			if errs := p.ErrorList(); len(errs) != 0 {
+				printErrors(w, line, errs, color)
+				continue
+			}
 
-			for {
-				t := l.NextToken()
+			for t := range lexer.New(line).All() {
 				fmt.Fprintf(w, "typ: %s # literal: %s\n", t.Typ, t.Literal)
-				if t.Typ == token.EOF {
-					break
-				}
 			}
 		}
 
 	}
 }
+
+func printErrors(w io.Writer, line string, errs parser.ErrorList, color bool) {
+	pr := diag.NewPrinter(line, color)
+	for _, e := range errs {
+		d := diag.Diagnostic{Severity: diag.Error, File: "<repl>", Pos: e.Pos, Msg: e.Msg}
+		for _, l := range e.Labels {
+			d.Labels = append(d.Labels, diag.Label{Pos: l.Pos, Msg: l.Msg})
+		}
+		pr.Print(w, d)
+	}
+}
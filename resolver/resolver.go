@@ -0,0 +1,197 @@
+// Package resolver links identifiers to their declarations. Declarations
+// are made by let statements and for loop variables. A let name is visible
+// from the statement after its let until the end of the enclosing scope or
+// until it is declared again. Every block is a scope, a for loop variable
+// is declared in the scope of the loop body
+package resolver
+
+import (
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/suggest"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Scope is a lexical scope. The program is the root scope
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+	// Node opens the scope
+	Node ast.Node
+	// Decls in source order
+	Decls []*Decl
+
+	// current declaration of each name while resolving
+	names map[string]*Decl
+}
+
+// lookup finds the declaration of the name visible at the current point
+// of resolution, going up to the enclosing scopes
+func (s *Scope) lookup(name string) *Decl {
+	for ; s != nil; s = s.Parent {
+		if d, ok := s.names[name]; ok {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// visible lists the names visible at the current point of resolution
+func (s *Scope) visible() []string {
+	var names []string
+	for ; s != nil; s = s.Parent {
+		for name := range s.names {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func newScope(parent *Scope, n ast.Node) *Scope {
+	s := &Scope{Parent: parent, Node: n, names: make(map[string]*Decl)}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+
+	return s
+}
+
+// Decl is a declaration of a name
+type Decl struct {
+	Name  string
+	Ident *ast.IdentifierEx
+	// Node declares the name: *ast.LetSt or *ast.ForSt
+	Node  ast.Node
+	Scope *Scope
+	// Uses of the declaration in source order
+	Uses []*ast.IdentifierEx
+	// Shadows is the earlier declaration of the same name
+	// in this or an enclosing scope, hidden by this one
+	Shadows *Decl
+}
+
+// Info is the result of resolution
+type Info struct {
+	Root *Scope
+	// Decls in source order
+	Decls []*Decl
+	// Defs maps declaring identifiers to their declarations
+	Defs map[*ast.IdentifierEx]*Decl
+	// Uses maps used identifiers to their declarations
+	Uses map[*ast.IdentifierEx]*Decl
+	// Undefined are the used identifiers without declarations in source order
+	Undefined []*ast.IdentifierEx
+	// Suggestions are the visible names or keywords closest to the
+	// undefined identifiers, for those that have one
+	Suggestions map[*ast.IdentifierEx]string
+}
+
+// DeclOf returns the declaration of the identifier, whether the identifier
+// declares the name or uses it. Nil for undefined identifiers
+func (info *Info) DeclOf(id *ast.IdentifierEx) *Decl {
+	if d, ok := info.Defs[id]; ok {
+		return d
+	}
+
+	return info.Uses[id]
+}
+
+// Resolve links the identifiers of the program to their declarations
+func Resolve(prg *ast.Program) *Info {
+	r := resolver{
+		info: &Info{
+			Defs:        make(map[*ast.IdentifierEx]*Decl),
+			Uses:        make(map[*ast.IdentifierEx]*Decl),
+			Suggestions: make(map[*ast.IdentifierEx]string),
+		},
+	}
+
+	r.info.Root = newScope(nil, prg)
+	r.statements(prg.StNodes, r.info.Root)
+
+	return r.info
+}
+
+type resolver struct {
+	info *Info
+}
+
+func (r *resolver) statements(sts []ast.Node, s *Scope) {
+	for _, st := range sts {
+		r.statement(st, s)
+	}
+}
+
+func (r *resolver) statement(st ast.Node, s *Scope) {
+	switch st := st.(type) {
+	case *ast.LetSt:
+		// the expression can't see the name being declared
+		r.expr(st.Expr, s)
+		if st.Ident != nil {
+			r.declare(st.Ident, st, s)
+		}
+	case *ast.ReturnSt:
+		r.expr(st.Expr, s)
+	case *ast.ExpressionSt:
+		r.expr(st.Expr, s)
+	case *ast.WhileSt:
+		r.expr(st.Cond, s)
+		r.block(st.Body, newScope(s, st.Body))
+	case *ast.ForSt:
+		r.expr(st.Collection, s)
+
+		body := newScope(s, st.Body)
+		if st.Ident != nil {
+			r.declare(st.Ident, st, body)
+		}
+		r.block(st.Body, body)
+	case *ast.BlockSt:
+		r.block(st, newScope(s, st))
+	}
+}
+
+func (r *resolver) block(b *ast.BlockSt, s *Scope) {
+	if b != nil {
+		r.statements(b.StNodes, s)
+	}
+}
+
+func (r *resolver) expr(e ast.ExprNode, s *Scope) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentifierEx); ok {
+			r.use(id, s)
+		}
+		return true
+	})
+}
+
+func (r *resolver) declare(id *ast.IdentifierEx, n ast.Node, s *Scope) {
+	d := &Decl{
+		Name:    id.Value,
+		Ident:   id,
+		Node:    n,
+		Scope:   s,
+		Shadows: s.lookup(id.Value),
+	}
+
+	s.Decls = append(s.Decls, d)
+	s.names[id.Value] = d
+
+	r.info.Decls = append(r.info.Decls, d)
+	r.info.Defs[id] = d
+}
+
+func (r *resolver) use(id *ast.IdentifierEx, s *Scope) {
+	d := s.lookup(id.Value)
+	if d == nil {
+		r.info.Undefined = append(r.info.Undefined, id)
+		if name := suggest.Closest(id.Value, append(s.visible(), token.Keywords()...)); name != "" {
+			r.info.Suggestions[id] = name
+		}
+		return
+	}
+
+	d.Uses = append(d.Uses, id)
+	r.info.Uses[id] = d
+}
@@ -0,0 +1,130 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+)
+
+func resolve(t *testing.T, src string) (*ast.Program, *Info) {
+	p := parser.New(lexer.New(src))
+	prg := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser got errors: %v", p.Errors())
+	}
+
+	return prg, Resolve(prg)
+}
+
+func TestResolve(t *testing.T) {
+	prg, info := resolve(t, `
+	let a = 1;
+	let b = a + c;
+	let a = a * b;
+	a;
+	`)
+
+	if len(info.Decls) != 3 {
+		t.Fatalf("Expected 3 declarations, got %d", len(info.Decls))
+	}
+	a1, b, a2 := info.Decls[0], info.Decls[1], info.Decls[2]
+
+	if a1.Name != "a" || b.Name != "b" || a2.Name != "a" {
+		t.Fatalf("Unexpected declarations %s, %s, %s", a1.Name, b.Name, a2.Name)
+	}
+
+	if a1.Node != prg.StNodes[0] || a1.Scope != info.Root {
+		t.Fatal("First declaration is not linked to its let statement and scope")
+	}
+
+	if a2.Shadows != a1 || a1.Shadows != nil || b.Shadows != nil {
+		t.Fatal("Wrong shadowing")
+	}
+
+	// a in b and in the second a declaration refer to the first a
+	if len(a1.Uses) != 2 || len(b.Uses) != 1 || len(a2.Uses) != 1 {
+		t.Fatalf("Expected 2, 1 and 1 uses, got %d, %d and %d", len(a1.Uses), len(b.Uses), len(a2.Uses))
+	}
+
+	last := prg.StNodes[3].(*ast.ExpressionSt).Expr.(*ast.IdentifierEx)
+	if info.Uses[last] != a2 || info.DeclOf(last) != a2 || info.DeclOf(a2.Ident) != a2 {
+		t.Fatal("Last a is not linked to the second declaration")
+	}
+
+	if len(info.Undefined) != 1 || info.Undefined[0].Value != "c" {
+		t.Fatalf("Expected c to be undefined, got %v", info.Undefined)
+	}
+}
+
+func TestResolveSelfReference(t *testing.T) {
+	_, info := resolve(t, "let x = x + 1;")
+
+	if len(info.Undefined) != 1 || info.Undefined[0].Value != "x" {
+		t.Fatal("Let expression must not see the name being declared")
+	}
+
+	if len(info.Decls[0].Uses) != 0 {
+		t.Fatal("Declaration got used by its own expression")
+	}
+}
+
+func TestResolveSuggestions(t *testing.T) {
+	_, info := resolve(t, "let count = 1; while (count) { let total = 2; totl + cuont; } totl; retrun; x;")
+
+	expected := []string{"total", "count", "", "return", ""}
+	if len(info.Undefined) != len(expected) {
+		t.Fatalf("Expected %d undefined names, got %v", len(expected), info.Undefined)
+	}
+
+	for i, id := range info.Undefined {
+		if got := info.Suggestions[id]; got != expected[i] {
+			t.Fatalf("Suggestion for %s at %s: expected %q, got %q", id.Value, id.Token.Pos, expected[i], got)
+		}
+	}
+}
+
+func TestResolveLoops(t *testing.T) {
+	prg, info := resolve(t, `
+	let x = 1;
+	for (x in xs) {
+		let y = x;
+		while (y) {
+			let x = y;
+		}
+	}
+	x;
+	`)
+
+	if len(info.Undefined) != 1 || info.Undefined[0].Value != "xs" {
+		t.Fatalf("Expected xs to be undefined, got %v", info.Undefined)
+	}
+
+	if len(info.Decls) != 4 {
+		t.Fatalf("Expected 4 declarations, got %d", len(info.Decls))
+	}
+	outer, loopVar, y, inner := info.Decls[0], info.Decls[1], info.Decls[2], info.Decls[3]
+
+	forSt := prg.StNodes[1].(*ast.ForSt)
+	if loopVar.Node != forSt || loopVar.Scope.Node != forSt.Body || loopVar.Scope.Parent != info.Root {
+		t.Fatal("Loop variable is not declared in the body scope")
+	}
+
+	if loopVar.Shadows != outer || inner.Shadows != loopVar || y.Shadows != nil {
+		t.Fatal("Wrong shadowing")
+	}
+
+	if len(loopVar.Uses) != 1 || len(y.Uses) != 2 || len(inner.Uses) != 0 {
+		t.Fatalf("Expected 1, 2 and 0 uses, got %d, %d and %d", len(loopVar.Uses), len(y.Uses), len(inner.Uses))
+	}
+
+	// the loop scopes end with the loop
+	if len(outer.Uses) != 1 {
+		t.Fatalf("Expected x after the loop to refer to the outer x")
+	}
+
+	if len(info.Root.Children) != 1 || len(info.Root.Children[0].Children) != 1 {
+		t.Fatal("Expected nested scopes of the for and while bodies")
+	}
+}
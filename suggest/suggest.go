@@ -0,0 +1,75 @@
+// Package suggest finds the names that a misspelled name was meant to be,
+// for the "did you mean" hints of the errors
+package suggest
+
+import "unicode/utf8"
+
+// Distance is the number of single character edits that turn one string
+// into the other. Edits are inserting, deleting or replacing a character
+// and swapping two adjacent characters
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// three rows of the table: two rows ago, previous and current
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+// Closest returns the candidate nearest to the name, if it is near
+// enough: at most a third of the name's characters are edited, and
+// not all of them. Ties go to the candidate that is first in order.
+// Empty if no candidate is near enough
+func Closest(name string, candidates []string) string {
+	n := utf8.RuneCountInString(name)
+	limit := max(n/3, 1)
+	if limit >= n {
+		limit = n - 1
+	}
+
+	best, bestDist := "", limit+1
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+
+		d := Distance(name, c)
+		if d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+
+	return best
+}
+
+// Hint is the text added to a message to suggest the name, e.g.
+// "undefined: retrun" + Hint("return"). Empty for the empty name
+func Hint(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return ", did you mean " + name + "?"
+}
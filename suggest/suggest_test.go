@@ -0,0 +1,67 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"let", "let", 0},
+		{"retrun", "return", 1},
+		{"whiel", "while", 1},
+		{"contine", "continue", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+		{"café", "cafe", 1},
+		{"été", "ete", 2},
+	}
+
+	for _, tst := range tests {
+		if got := Distance(tst.a, tst.b); got != tst.expected {
+			t.Fatalf("Distance(%q, %q): expected %d, got %d", tst.a, tst.b, tst.expected, got)
+		}
+		if got := Distance(tst.b, tst.a); got != tst.expected {
+			t.Fatalf("Distance(%q, %q): expected %d, got %d", tst.b, tst.a, tst.expected, got)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	keywords := []string{"fn", "let", "return", "while", "for", "in", "break", "continue"}
+
+	tests := []struct {
+		name       string
+		candidates []string
+		expected   string
+	}{
+		{"retrun", keywords, "return"},
+		{"brek", keywords, "break"},
+		{"fro", keywords, "for"},
+		{"lte", keywords, "let"},
+		{"x", []string{"y", "xs"}, ""},
+		{"counter", []string{"count", "cointer", "counted"}, "cointer"},
+		{"abcd", []string{"abce", "abcf"}, "abce"},
+		{"total", []string{"total", "totals"}, "totals"},
+		{"something", keywords, ""},
+		{"", keywords, ""},
+	}
+
+	for _, tst := range tests {
+		if got := Closest(tst.name, tst.candidates); got != tst.expected {
+			t.Fatalf("Closest(%q): expected %q, got %q", tst.name, tst.expected, got)
+		}
+	}
+}
+
+func TestHint(t *testing.T) {
+	if got := Hint("length"); got != ", did you mean length?" {
+		t.Fatalf("Unexpected hint %q", got)
+	}
+
+	if got := Hint(""); got != "" {
+		t.Fatalf("Expected no hint, got %q", got)
+	}
+}
@@ -1,12 +1,25 @@
 package token
 
+import "strconv"
+
 // Typ is token type
 type Typ string
 
+// Pos is a position in the source code. Both line and column start at 1
+type Pos struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+func (p Pos) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Col)
+}
+
 // Token of the Monkey PL
 type Token struct {
-	Typ     Typ
-	Literal string
+	Typ     Typ    `json:"typ"`
+	Literal string `json:"literal"`
+	Pos     Pos    `json:"pos"`
 }
 
 // token types
@@ -14,19 +27,32 @@ const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
+	// COMMENT is only produced by lexers made with lexer.NewWithComments
+	COMMENT = "COMMENT"
+
 	// ops
 	PLUS   = "+"
 	ASSIGN = "="
 
+	// compound assignments
+	PLUS_ASSIGN   = "+="
+	MINUS_ASSIGN  = "-="
+	MULT_ASSIGN   = "*="
+	DIVIDE_ASSIGN = "/="
+
 	//delims
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ARROW     = "->"
 
 	// parens
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// keywords
 	FUNCTION = "FUNCTION"
@@ -36,6 +62,11 @@ const (
 	RETURN   = "RETURN"
 	FALSE    = "FALSE"
 	TRUE     = "TRUE"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 
 	// ops
 	NOT     = "!"
@@ -46,9 +77,25 @@ const (
 	GREATER = ">"
 	EQ      = "=="
 	NEQ     = "!="
+	AND     = "&&"
+	OR      = "||"
+
+	LESS_EQ    = "<="
+	GREATER_EQ = ">="
+	MOD        = "%"
+	POWER      = "**"
+
+	// bitwise ops
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	BIT_NOT     = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 
 	IDENT = "IDENT"
 
 	// literals
-	INT = "INT"
+	INT   = "INT"
+	FLOAT = "FLOAT"
 )
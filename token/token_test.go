@@ -1 +1,50 @@
 package token
+
+import "testing"
+
+func TestCategories(t *testing.T) {
+	tests := []struct {
+		typ                        Typ
+		keyword, operator, literal bool
+		precedence                 int
+	}{
+		{LET, true, false, false, LowestPrec},
+		{TRUE, true, false, false, LowestPrec},
+		{CONTINUE, true, false, false, LowestPrec},
+		{IDENT, false, false, true, LowestPrec},
+		{INT, false, false, true, LowestPrec},
+		{FLOAT, false, false, true, LowestPrec},
+		{PLUS, false, true, false, SumPrec},
+		{MINUS, false, true, false, SumPrec},
+		{NOT, false, true, false, LowestPrec},
+		{BIT_NOT, false, true, false, LowestPrec},
+		{POWER, false, true, false, PowerPrec},
+		{AND, false, true, false, AndPrec},
+		{PLUS_ASSIGN, false, true, false, AssignPrec},
+		{LBRACKET, false, false, false, IndexPrec},
+		{SEMICOLON, false, false, false, LowestPrec},
+		{COMMENT, false, false, false, LowestPrec},
+		{EOF, false, false, false, LowestPrec},
+	}
+
+	for _, tst := range tests {
+		if tst.typ.IsKeyword() != tst.keyword || tst.typ.IsOperator() != tst.operator ||
+			tst.typ.IsLiteral() != tst.literal || tst.typ.Precedence() != tst.precedence {
+			t.Fatalf("Wrong categories of %s: keyword %t, operator %t, literal %t, precedence %d",
+				tst.typ, tst.typ.IsKeyword(), tst.typ.IsOperator(), tst.typ.IsLiteral(), tst.typ.Precedence())
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	for _, kw := range Keywords() {
+		typ, ok := LookupKeyword(kw)
+		if !ok || !typ.IsKeyword() {
+			t.Fatalf("Keyword %q is not recognized", kw)
+		}
+	}
+
+	if _, ok := LookupKeyword("lets"); ok {
+		t.Fatal("lets is not a keyword")
+	}
+}
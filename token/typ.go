@@ -0,0 +1,125 @@
+package token
+
+import "sort"
+
+// binding powers of the operators, from the weakest. The parser
+// re-exports them under its own names
+const (
+	_ int = iota
+	LowestPrec
+	AssignPrec
+	OrPrec
+	AndPrec
+	EqPrec
+	LessGrPrec
+	BitOrPrec
+	BitXorPrec
+	BitAndPrec
+	ShiftPrec
+	SumPrec
+	ProductPrec
+	PrefixPrec
+	PowerPrec
+	CallPrec
+	IndexPrec
+)
+
+var precedences = map[Typ]int{
+	ASSIGN:        AssignPrec,
+	PLUS_ASSIGN:   AssignPrec,
+	MINUS_ASSIGN:  AssignPrec,
+	MULT_ASSIGN:   AssignPrec,
+	DIVIDE_ASSIGN: AssignPrec,
+	OR:            OrPrec,
+	AND:           AndPrec,
+	EQ:            EqPrec,
+	NEQ:           EqPrec,
+	LESS:          LessGrPrec,
+	GREATER:       LessGrPrec,
+	LESS_EQ:       LessGrPrec,
+	GREATER_EQ:    LessGrPrec,
+	BIT_OR:        BitOrPrec,
+	BIT_XOR:       BitXorPrec,
+	BIT_AND:       BitAndPrec,
+	SHIFT_LEFT:    ShiftPrec,
+	SHIFT_RIGHT:   ShiftPrec,
+	PLUS:          SumPrec,
+	MINUS:         SumPrec,
+	DIVIDE:        ProductPrec,
+	MULT:          ProductPrec,
+	MOD:           ProductPrec,
+	POWER:         PowerPrec,
+	LPAREN:        CallPrec,
+	LBRACKET:      IndexPrec,
+}
+
+var keywords = map[string]Typ{
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"false":    FALSE,
+	"true":     TRUE,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+}
+
+var operators = map[Typ]bool{
+	PLUS: true, MINUS: true, MULT: true, DIVIDE: true, MOD: true, POWER: true,
+	NOT: true, AND: true, OR: true,
+	LESS: true, GREATER: true, LESS_EQ: true, GREATER_EQ: true, EQ: true, NEQ: true,
+	BIT_AND: true, BIT_OR: true, BIT_XOR: true, BIT_NOT: true, SHIFT_LEFT: true, SHIFT_RIGHT: true,
+	ASSIGN: true, PLUS_ASSIGN: true, MINUS_ASSIGN: true, MULT_ASSIGN: true, DIVIDE_ASSIGN: true,
+}
+
+// LookupKeyword returns the type of the keyword. Reports if the word is one
+func LookupKeyword(word string) (Typ, bool) {
+	typ, ok := keywords[word]
+	return typ, ok
+}
+
+// Keywords returns all the keywords in alphabetical order
+func Keywords() []string {
+	kws := make([]string, 0, len(keywords))
+	for kw := range keywords {
+		kws = append(kws, kw)
+	}
+	sort.Strings(kws)
+
+	return kws
+}
+
+// IsKeyword tells if the type is of a keyword, true and false included
+func (t Typ) IsKeyword() bool {
+	for _, typ := range keywords {
+		if typ == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsOperator tells if the type is of a prefix, infix or assignment operator
+func (t Typ) IsOperator() bool {
+	return operators[t]
+}
+
+// IsLiteral tells if the type is of an identifier or a number
+func (t Typ) IsLiteral() bool {
+	return t == IDENT || t == INT || t == FLOAT
+}
+
+// Precedence returns the binding power of the infix operator type.
+// Everything that is not an infix operator gets LowestPrec
+func (t Typ) Precedence() int {
+	if prec, ok := precedences[t]; ok {
+		return prec
+	}
+
+	return LowestPrec
+}
@@ -0,0 +1,568 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/suggest"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// Error is a type error at a position in the code
+type Error struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Info has the results of checking
+type Info struct {
+	// Types of the expressions
+	Types map[ast.ExprNode]Type
+	// Defs are the generalized types of the let bindings
+	Defs map[*ast.IdentifierEx]*Scheme
+}
+
+// TypeOf returns the type of the expression with the variables
+// resolved as far as inference got
+func (info *Info) TypeOf(e ast.ExprNode) Type {
+	if t, ok := info.Types[e]; ok {
+		return prune(t)
+	}
+
+	return nil
+}
+
+// Check infers the types in the program. Checking goes on after an error,
+// so all the errors are found in one go
+func Check(prg *ast.Program) (*Info, []Error) {
+	c := checker{
+		env: make(map[string]*Scheme),
+		info: &Info{
+			Types: make(map[ast.ExprNode]Type),
+			Defs:  make(map[*ast.IdentifierEx]*Scheme),
+		},
+	}
+
+	for _, st := range prg.StNodes {
+		c.statement(st)
+	}
+
+	return c.info, c.errs
+}
+
+type checker struct {
+	nextVar int
+	// the bindings visible at the current statement
+	env map[string]*Scheme
+
+	info *Info
+	errs []Error
+}
+
+// closest finds the binding or keyword the undefined name is likely
+// a misspelling of
+func (c *checker) closest(name string) string {
+	candidates := token.Keywords()
+	for n := range c.env {
+		candidates = append(candidates, n)
+	}
+
+	return suggest.Closest(name, candidates)
+}
+
+func (c *checker) errorf(pos token.Pos, format string, args ...interface{}) {
+	c.errs = append(c.errs, Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) statement(st ast.Node) {
+	switch st := st.(type) {
+	case *ast.LetSt:
+		t := c.expr(st.Expr)
+
+		if st.Type != nil {
+			want := c.annotation(st.Type)
+			if !c.unify(t, want) {
+				c.errorf(st.Ident.Token.Pos, "cannot use %s as %s in let of %s", t, want, st.Ident.Value)
+				t = want
+			}
+		}
+
+		s := c.generalize(t)
+		c.info.Defs[st.Ident] = s
+		c.env[st.Ident.Value] = s
+	case *ast.ReturnSt:
+		c.expr(st.Expr)
+	case *ast.ExpressionSt:
+		c.expr(st.Expr)
+	case *ast.WhileSt:
+		// anything has a truth value
+		c.expr(st.Cond)
+		c.block(st.Body, nil)
+	case *ast.ForSt:
+		elem := c.element(st.Collection)
+		c.block(st.Body, func() {
+			s := &Scheme{Type: elem}
+			c.info.Defs[st.Ident] = s
+			c.env[st.Ident.Value] = s
+		})
+	case *ast.BlockSt:
+		c.block(st, nil)
+	}
+}
+
+// block checks the statements in a scope of their own. declare binds
+// the names of the scope that come before the statements
+func (c *checker) block(b *ast.BlockSt, declare func()) {
+	outer := c.env
+	c.env = make(map[string]*Scheme, len(outer))
+	for name, s := range outer {
+		c.env[name] = s
+	}
+
+	if declare != nil {
+		declare()
+	}
+	for _, st := range b.StNodes {
+		c.statement(st)
+	}
+
+	c.env = outer
+}
+
+// element returns the type of the loop variable iterating over the
+// collection: elements of arrays, keys of hashes, characters of strings
+func (c *checker) element(coll ast.ExprNode) Type {
+	t := c.expr(coll)
+
+	con, ok := prune(t).(*Con)
+	if !ok {
+		// not known yet, so can be anything iterable
+		return c.newVar()
+	}
+
+	switch con.Name {
+	case ArrayName, HashName:
+		return con.Args[0]
+	case StringName:
+		return String
+	}
+
+	c.errorf(ast.Pos(coll), "cannot iterate over %s", con)
+	return c.newVar()
+}
+
+// annotation converts the type annotation to the type
+func (c *checker) annotation(te *ast.TypeExpr) Type {
+	args := make([]Type, len(te.Args))
+	for i, a := range te.Args {
+		args[i] = c.annotation(a)
+	}
+
+	switch te.Token.Typ {
+	case token.LBRACKET:
+		return Array(args[0])
+	case token.LBRACE:
+		return Hash(args[0], args[1])
+	case token.FUNCTION:
+		return Func(args[:len(args)-1], args[len(args)-1])
+	}
+
+	switch te.Name {
+	case IntName:
+		return Int
+	case BoolName:
+		return Bool
+	case FloatName:
+		return Float
+	case StringName:
+		return String
+	}
+
+	c.errorf(te.Token.Pos, "unknown type %s", te.Name)
+	return c.newVar()
+}
+
+func (c *checker) expr(e ast.ExprNode) Type {
+	t := c.inferExpr(e)
+	if e != nil {
+		c.info.Types[e] = t
+	}
+
+	return t
+}
+
+func (c *checker) inferExpr(e ast.ExprNode) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteralEx:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.BooleanLiteral:
+		return Bool
+	case *ast.IdentifierEx:
+		s, ok := c.env[e.Value]
+		if !ok {
+			c.errorf(e.Token.Pos, "undefined: %s%s", e.Value, suggest.Hint(c.closest(e.Value)))
+			return c.newVar()
+		}
+		return c.instantiate(s)
+	case *ast.PrefixExpr:
+		right := c.expr(e.Right)
+
+		switch e.Op {
+		case "-":
+			if isFloat(right) {
+				return Float
+			}
+			c.expect(e.Token.Pos, e.Op, right, Int)
+			return Int
+		case "!":
+			// anything has a truth value
+			return Bool
+		case "~":
+			c.expect(e.Token.Pos, e.Op, right, Int)
+			return Int
+		}
+	case *ast.InfixExpr:
+		return c.infix(e)
+	case *ast.IndexExpr:
+		return c.index(e)
+	case *ast.AssignExpr:
+		return c.assign(e)
+	}
+
+	return c.newVar()
+}
+
+// index gives the type of an element: arrays are indexed by int,
+// hashes by their keys, strings by int giving a string
+func (c *checker) index(e *ast.IndexExpr) Type {
+	left := c.expr(e.Left)
+	index := c.expr(e.Index)
+
+	con, ok := prune(left).(*Con)
+	if !ok {
+		// not known yet, take it for an array
+		elem := c.newVar()
+		c.unify(left, Array(elem))
+		c.expect(e.Token.Pos, e.Token.Literal, index, Int)
+		return elem
+	}
+
+	switch con.Name {
+	case ArrayName:
+		c.expect(e.Token.Pos, e.Token.Literal, index, Int)
+		return con.Args[0]
+	case HashName:
+		if !c.unify(index, con.Args[0]) {
+			c.errorf(ast.Pos(e.Index), "cannot use %s as key of %s", index, con)
+		}
+		return con.Args[1]
+	case StringName:
+		c.expect(e.Token.Pos, e.Token.Literal, index, Int)
+		return String
+	}
+
+	c.errorf(e.Token.Pos, "cannot index %s", con)
+	return c.newVar()
+}
+
+// assign checks that the value fits the target. A compound assignment
+// is checked as its arithmetic operator. The type of the assignment is
+// the type of the target
+func (c *checker) assign(e *ast.AssignExpr) Type {
+	if id, ok := e.Target.(*ast.IdentifierEx); ok {
+		if _, ok := c.env[id.Value]; !ok {
+			c.errorf(id.Token.Pos, "assignment to undeclared name %s%s", id.Value, suggest.Hint(c.closest(id.Value)))
+			c.env[id.Value] = &Scheme{Type: c.newVar()}
+		}
+	}
+
+	target := c.expr(e.Target)
+	value := c.expr(e.Value)
+	pos := e.Token.Pos
+
+	if e.Op != "=" {
+		errs := len(c.errs)
+		value = c.binary(pos, e.Op[:len(e.Op)-1], e.Op, target, value, e.Value)
+		if len(c.errs) != errs {
+			// the operator is reported already
+			return target
+		}
+	}
+
+	if !c.unify(target, value) {
+		c.errorf(pos, "cannot assign %s to %s of type %s", value, e.Target, target)
+	}
+
+	return target
+}
+
+func (c *checker) infix(e *ast.InfixExpr) Type {
+	left := c.expr(e.Left)
+	right := c.expr(e.Right)
+
+	return c.binary(e.OpToken.Pos, e.Op, e.Op, left, right, e.Right)
+}
+
+// binary gives the type of the binary operation. shown is the operator
+// in the code, it differs from op for the compound assignments
+func (c *checker) binary(pos token.Pos, op, shown string, left, right Type, rightExpr ast.ExprNode) Type {
+	switch op {
+	case "+":
+		// adds numbers and concatenates strings
+		if isFloat(left) || isFloat(right) {
+			return c.arith(pos, shown, left, right)
+		}
+		if !c.unify(left, right) {
+			c.errorf(pos, "mismatched types %s and %s for %s", left, right, shown)
+			return c.newVar()
+		}
+		if con, ok := prune(left).(*Con); ok && con.Name != IntName && con.Name != StringName {
+			c.errorf(pos, "operator %s is not defined on %s", shown, con)
+		}
+		return left
+	case "-", "*", "/", "**":
+		c.divisor(op, rightExpr)
+		return c.arith(pos, shown, left, right)
+	case "%", "&", "|", "^", "<<", ">>":
+		c.expectOperands(pos, shown, left, right, Int)
+		c.divisor(op, rightExpr)
+		return Int
+	case "<", ">", "<=", ">=":
+		c.arith(pos, shown, left, right)
+		return Bool
+	case "==", "!=":
+		// ints and floats compare by value
+		if (isFloat(left) || isFloat(right)) && c.number(left) && c.number(right) {
+			return Bool
+		}
+		if !c.unify(left, right) {
+			c.errorf(pos, "mismatched types %s and %s for %s", left, right, shown)
+		}
+		return Bool
+	case "&&", "||":
+		// the value is the operand that decided the result,
+		// so both have to be of the same type
+		if !c.unify(left, right) {
+			c.errorf(pos, "mismatched types %s and %s for %s", left, right, shown)
+			return c.newVar()
+		}
+		return left
+	}
+
+	return c.newVar()
+}
+
+// arith gives the type of the arithmetic on numbers. If one of the
+// operands is float, the other is promoted to float and so is the result.
+// Otherwise both have to be ints
+func (c *checker) arith(pos token.Pos, op string, left, right Type) Type {
+	if !isFloat(left) && !isFloat(right) {
+		c.expectOperands(pos, op, left, right, Int)
+		return Int
+	}
+
+	// both are checked, one error is enough
+	leftOK, rightOK := c.number(left), c.number(right)
+	switch {
+	case !leftOK:
+		c.errorf(pos, "operator %s needs a number, got %s", op, left)
+	case !rightOK:
+		c.errorf(pos, "operator %s needs a number, got %s", op, right)
+	}
+
+	return Float
+}
+
+// number tells if the type is int or float. A type that is not known
+// yet becomes float
+func (c *checker) number(t Type) bool {
+	if _, ok := prune(t).(*Var); ok {
+		return c.unify(t, Float)
+	}
+
+	return isFloat(t) || c.unify(t, Int)
+}
+
+func isFloat(t Type) bool {
+	con, ok := prune(t).(*Con)
+	return ok && con.Name == FloatName
+}
+
+// divisor reports division and modulo by a literal zero
+func (c *checker) divisor(op string, right ast.ExprNode) {
+	lit, ok := right.(*ast.IntegerLiteralEx)
+	if !ok || lit.Value != 0 || lit.Big != nil {
+		return
+	}
+
+	switch op {
+	case "/":
+		c.errorf(lit.Token.Pos, "division by zero")
+	case "%":
+		c.errorf(lit.Token.Pos, "modulo by zero")
+	}
+}
+
+// expect reports an error if an operand of the operator is not of the type
+func (c *checker) expect(pos token.Pos, op string, got, want Type) {
+	if !c.unify(got, want) {
+		c.errorf(pos, "operator %s needs %s, got %s", op, want, got)
+	}
+}
+
+// expectOperands is expect for both operands of a binary operator.
+// Both are unified with the type, only the first bad one is reported
+func (c *checker) expectOperands(pos token.Pos, op string, left, right, want Type) {
+	leftOK, rightOK := c.unify(left, want), c.unify(right, want)
+	switch {
+	case !leftOK:
+		c.errorf(pos, "operator %s needs %s, got %s", op, want, left)
+	case !rightOK:
+		c.errorf(pos, "operator %s needs %s, got %s", op, want, right)
+	}
+}
+
+func (c *checker) newVar() *Var {
+	c.nextVar++
+	return &Var{id: c.nextVar}
+}
+
+// unify makes the types equal binding the variables. Reports if it's possible
+func (c *checker) unify(a, b Type) bool {
+	a, b = prune(a), prune(b)
+
+	if va, ok := a.(*Var); ok {
+		if va == b {
+			return true
+		}
+		if occurs(va, b) {
+			return false
+		}
+		va.instance = b
+		return true
+	}
+
+	if _, ok := b.(*Var); ok {
+		return c.unify(b, a)
+	}
+
+	ca, cb := a.(*Con), b.(*Con)
+	if ca.Name != cb.Name || len(ca.Args) != len(cb.Args) {
+		return false
+	}
+
+	for i := range ca.Args {
+		if !c.unify(ca.Args[i], cb.Args[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// occurs tells if the variable is inside the type
+func occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Con:
+		for _, a := range t.Args {
+			if occurs(v, a) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// generalize makes a scheme over the variables that are free in the type
+// but not in the environment
+func (c *checker) generalize(t Type) *Scheme {
+	inEnv := make(map[*Var]bool)
+	for _, s := range c.env {
+		bound := make(map[*Var]bool)
+		for _, v := range s.Vars {
+			bound[v] = true
+		}
+		for _, v := range freeVars(s.Type) {
+			if !bound[v] {
+				inEnv[v] = true
+			}
+		}
+	}
+
+	s := &Scheme{Type: t}
+	for _, v := range freeVars(t) {
+		if !inEnv[v] {
+			s.Vars = append(s.Vars, v)
+		}
+	}
+
+	return s
+}
+
+// instantiate replaces the variables of the scheme with fresh ones
+func (c *checker) instantiate(s *Scheme) Type {
+	if len(s.Vars) == 0 {
+		return s.Type
+	}
+
+	fresh := make(map[*Var]Type)
+	for _, v := range s.Vars {
+		fresh[v] = c.newVar()
+	}
+
+	return substitute(s.Type, fresh)
+}
+
+func substitute(t Type, sub map[*Var]Type) Type {
+	switch t := prune(t).(type) {
+	case *Var:
+		if r, ok := sub[t]; ok {
+			return r
+		}
+		return t
+	case *Con:
+		if len(t.Args) == 0 {
+			return t
+		}
+		args := make([]Type, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = substitute(a, sub)
+		}
+		return &Con{Name: t.Name, Args: args}
+	}
+
+	return t
+}
+
+// freeVars returns the unbound variables of the type in order of appearance
+func freeVars(t Type) []*Var {
+	var vars []*Var
+	seen := make(map[*Var]bool)
+
+	var walk func(Type)
+	walk = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if !seen[t] {
+				seen[t] = true
+				vars = append(vars, t)
+			}
+		case *Con:
+			for _, a := range t.Args {
+				walk(a)
+			}
+		}
+	}
+	walk(t)
+
+	return vars
+}
@@ -0,0 +1,130 @@
+// Package types infers Hindley-Milner types of Monkey programs. let
+// bindings are generalized, so a binding can be used at different types.
+// Checking is optional, it never changes how the program runs
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Type is a Monkey type
+type Type interface {
+	String() string
+}
+
+// Con is a constructed type. The basic types have no arguments. Arrays have
+// the element type, hashes the key and value types and functions the
+// parameter types followed by the result type
+type Con struct {
+	Name string
+	Args []Type
+}
+
+// type constructor names
+const (
+	IntName    = "int"
+	FloatName  = "float"
+	BoolName   = "bool"
+	StringName = "string"
+	ArrayName  = "array"
+	HashName   = "hash"
+	FuncName   = "fn"
+)
+
+// the basic types
+var (
+	Int    = &Con{Name: IntName}
+	Float  = &Con{Name: FloatName}
+	Bool   = &Con{Name: BoolName}
+	String = &Con{Name: StringName}
+)
+
+// Array makes array type
+func Array(elem Type) *Con {
+	return &Con{Name: ArrayName, Args: []Type{elem}}
+}
+
+// Hash makes hash type
+func Hash(key, value Type) *Con {
+	return &Con{Name: HashName, Args: []Type{key, value}}
+}
+
+// Func makes function type
+func Func(params []Type, result Type) *Con {
+	args := append(append([]Type{}, params...), result)
+	return &Con{Name: FuncName, Args: args}
+}
+
+func (c *Con) String() string {
+	return typeString(c, make(map[*Var]string))
+}
+
+// Var is a type variable. It is bound to a type during unification
+type Var struct {
+	id       int
+	instance Type
+}
+
+func (v *Var) String() string {
+	return typeString(v, make(map[*Var]string))
+}
+
+// Scheme is a type generalized over some of its variables
+type Scheme struct {
+	Vars []*Var
+	Type Type
+}
+
+func (s *Scheme) String() string {
+	return typeString(s.Type, make(map[*Var]string))
+}
+
+// prune follows the bound variables to the actual type
+func prune(t Type) Type {
+	if v, ok := t.(*Var); ok && v.instance != nil {
+		v.instance = prune(v.instance)
+		return v.instance
+	}
+
+	return t
+}
+
+// typeString prints the type naming the free variables 'a, 'b, ...
+// in the order they appear
+func typeString(t Type, names map[*Var]string) string {
+	switch t := prune(t).(type) {
+	case *Var:
+		if _, ok := names[t]; !ok {
+			names[t] = varName(len(names))
+		}
+		return names[t]
+	case *Con:
+		args := make([]string, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = typeString(a, names)
+		}
+
+		switch t.Name {
+		case ArrayName:
+			return "[" + args[0] + "]"
+		case HashName:
+			return "{" + args[0] + ": " + args[1] + "}"
+		case FuncName:
+			return "fn(" + strings.Join(args[:len(args)-1], ", ") + ") -> " + args[len(args)-1]
+		}
+
+		return t.Name
+	}
+
+	return "?"
+}
+
+func varName(n int) string {
+	name := "'" + string(rune('a'+n%26))
+	if n >= 26 {
+		name += strconv.Itoa(n / 26)
+	}
+
+	return name
+}
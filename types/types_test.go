@@ -0,0 +1,163 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+)
+
+func check(t *testing.T, src string) (*ast.Program, *Info, []Error) {
+	p := parser.New(lexer.New(src))
+	prg := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser got errors: %v", p.Errors())
+	}
+
+	info, errs := Check(prg)
+
+	return prg, info, errs
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"1;", "int"},
+		{"-1 * 2;", "int"},
+		{"!5;", "bool"},
+		{"1 < 2;", "bool"},
+		{"1 + 2 == 3;", "bool"},
+		{"let a = 1 > 2; a == !a;", "bool"},
+		{"let a = 7; a + a;", "int"},
+		{"let a: bool = 1 > 0; a;", "bool"},
+		{"let a = 1; a > 0 && a < 2 || a == 5;", "bool"},
+		{"let a = 1; a && 2;", "int"},
+		{"1.5;", "float"},
+		{"let a = 2; a * 0.5 + 1;", "float"},
+		{"let a = 2; -(a / 2.0);", "float"},
+		{"let a = 2; a ** 0.5 <= a;", "bool"},
+		{"1.0 == 1;", "bool"},
+		{"let f: float = 1e3; f -= 1;", "float"},
+		{"let a = 7; a % 2 ** a << 1 | ~a & a ^ a >> 1;", "int"},
+		{"let a = 7; a <= 1 == (a >= 2);", "bool"},
+		{"let a = 1; a = a + 1;", "int"},
+		{"let a = 1; let b = 2; a = b = 3;", "int"},
+		{"true;", "bool"},
+		{"let b = false; !b == true || b;", "bool"},
+	}
+
+	for _, tst := range tests {
+		prg, info, errs := check(t, tst.in)
+		if len(errs) != 0 {
+			t.Fatalf("Checking %q failed: %v", tst.in, errs)
+		}
+
+		last := prg.StNodes[len(prg.StNodes)-1].(*ast.ExpressionSt)
+		if got := info.TypeOf(last.Expr).String(); got != tst.expected {
+			t.Fatalf("Expected type %s of %q, got %s", tst.expected, tst.in, got)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"let b = 1 < 2; 1 + b;", "1:18: mismatched types int and bool for +"},
+		{"let b = !1; b - 1;", "1:15: operator - needs int, got bool"},
+		{"let b = !1; -b;", "1:13: operator - needs int, got bool"},
+		{"let b = !1; b + b;", "1:15: operator + is not defined on bool"},
+		{"1 == (2 > 3);", "1:3: mismatched types int and bool for =="},
+		{"x + 1;", "1:1: undefined: x"},
+		{"let b = 1 < 2; b * b > 0;", "1:18: operator * needs int, got bool"},
+		{"let b: int = 1 < 2;", "1:5: cannot use bool as int in let of b"},
+		{"let b: [int] = 1; b + b;", "1:5: cannot use int as [int] in let of b\n1:21: operator + is not defined on [int]"},
+		{"let b: num = 1;", "1:8: unknown type num"},
+		{"for (x in 1 + 2) {}", "1:11: cannot iterate over int"},
+		{"let xs: [bool] = ys; for (x in xs) { x + 1; }", "1:18: undefined: ys\n1:40: mismatched types bool and int for +"},
+		{"let h: {string: int} = g; for (k in h) { k * 2; }", "1:24: undefined: g\n1:44: operator * needs int, got string"},
+		{"while (1) { let a = 1 < 2; } a;", "1:30: undefined: a"},
+		{"let b = 1 < 2; b || 1;", "1:18: mismatched types bool and int for ||"},
+		{"let b = 1 < 2; ~b;", "1:16: operator ~ needs int, got bool"},
+		{"let b = 1 < 2; b ** 2 <= 1;", "1:18: operator ** needs int, got bool"},
+		{"let a = 1; a / 0 + a % 0;", "1:16: division by zero\n1:24: modulo by zero"},
+		{"let a = 1; a /= 0; a / 00;", "1:17: division by zero\n1:24: division by zero"},
+		{"let a = 1; a / 0 + a % 99999999999999999999;", "1:16: division by zero"},
+		{"let a = 1; a += 0.5;", "1:14: cannot assign float to a of type int"},
+		{"let b = 1 < 2; b * 2.5;", "1:18: operator * needs a number, got bool"},
+		{"1.5 % 2;", "1:5: operator % needs int, got float"},
+		{"let f: float = 1; f;", "1:5: cannot use int as float in let of f"},
+		{"x = 1;", "1:1: assignment to undeclared name x"},
+		{"let a = 1; a = 1 < 2;", "1:14: cannot assign bool to a of type int"},
+		{"let a = 1; a -= !a;", "1:14: operator -= needs int, got bool"},
+		{"let a = 1; a += !a;", "1:14: mismatched types int and bool for +="},
+		{"let xs: [bool] = ys; xs[0] + 1;", "1:18: undefined: ys\n1:28: mismatched types bool and int for +"},
+		{"let xs: [int] = ys; xs[xs] = 1;", "1:17: undefined: ys\n1:23: operator [ needs int, got [int]"},
+		{"let h: {string: int} = g; h[1] *= 2;", "1:24: undefined: g\n1:29: cannot use int as key of {string: int}"},
+		{"let s: string = t; s[0] -= s;", "1:17: undefined: t\n1:25: operator -= needs int, got string"},
+		{"let a = 1; a[0];", "1:13: cannot index int"},
+		{"1 + true;", "1:3: mismatched types int and bool for +"},
+		{"true * false;", "1:6: operator * needs int, got bool"},
+		{"1 % true;", "1:3: operator % needs int, got bool"},
+		{"false * 1.5;", "1:7: operator * needs a number, got bool"},
+		{"1.5 * true;", "1:5: operator * needs a number, got bool"},
+		{"let count = 1; cuont + 1;", "1:16: undefined: cuont, did you mean count?"},
+		{"let total = 1; totl = 2;", "1:16: assignment to undeclared name totl, did you mean total?"},
+		{"while (1) { let inner = 1; } iner; ture;", "1:30: undefined: iner\n1:36: undefined: ture, did you mean true?"},
+	}
+
+	for _, tst := range tests {
+		_, _, errs := check(t, tst.in)
+
+		var got []string
+		for _, e := range errs {
+			got = append(got, e.Error())
+		}
+
+		if strings.Join(got, "\n") != tst.expected {
+			t.Fatalf("Checking %q: expected errors\n%s\ngot\n%s", tst.in, tst.expected, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestUnifyAndGeneralize(t *testing.T) {
+	c := checker{env: make(map[string]*Scheme)}
+
+	// fn('a) -> 'a is polymorphic, each use gets fresh variables
+	a := c.newVar()
+	id := c.generalize(Func([]Type{a}, a))
+	if len(id.Vars) != 1 || id.String() != "fn('a) -> 'a" {
+		t.Fatalf("Expected fn('a) -> 'a over one variable, got %s over %d", id, len(id.Vars))
+	}
+
+	first := c.instantiate(id)
+	second := c.instantiate(id)
+	if !c.unify(first, Func([]Type{Int}, c.newVar())) || !c.unify(second, Func([]Type{Bool}, c.newVar())) {
+		t.Fatal("Polymorphic function can't be used at two types")
+	}
+	if first.String() != "fn(int) -> int" || second.String() != "fn(bool) -> bool" {
+		t.Fatalf("Unexpected instances %s and %s", first, second)
+	}
+
+	// a variable in the environment is not generalized
+	b := c.newVar()
+	c.env["b"] = &Scheme{Type: b}
+	if s := c.generalize(Array(b)); len(s.Vars) != 0 {
+		t.Fatal("Variable of the environment got generalized")
+	}
+
+	// 'a = [a] is infinite
+	v := c.newVar()
+	if c.unify(v, Array(v)) {
+		t.Fatal("Infinite type got unified")
+	}
+
+	if h := Hash(String, Array(Int)); h.String() != "{string: [int]}" {
+		t.Fatalf("Unexpected hash type %s", h)
+	}
+}
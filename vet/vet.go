@@ -0,0 +1,187 @@
+// Package vet finds suspicious code: undefined and unused names, shadowed
+// declarations and unreachable statements. Every finding has a stable rule
+// ID. Findings on a line are suppressed by a comment on that line:
+//
+//	let tmp = 1; // vet:ignore
+//	let x = 2; // vet:ignore unused,shadow
+package vet
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/grzkv/m-interpreter/ast"
+	"github.com/grzkv/m-interpreter/lexer"
+	"github.com/grzkv/m-interpreter/parser"
+	"github.com/grzkv/m-interpreter/resolver"
+	"github.com/grzkv/m-interpreter/suggest"
+	"github.com/grzkv/m-interpreter/token"
+)
+
+// rule IDs
+const (
+	// RuleUndefined is a use of or an assignment to a name that is not declared
+	RuleUndefined = "undefined"
+	// RuleUnused is a let binding or a loop variable that is never used.
+	// Names starting with _ are exempt
+	RuleUnused = "unused"
+	// RuleShadow is a declaration of a name that is already declared
+	RuleShadow = "shadow"
+	// RuleUnreachable is a statement after return, break or continue
+	RuleUnreachable = "unreachable"
+)
+
+const ignoreDirective = "vet:ignore"
+
+// Finding is a problem found by vet
+type Finding struct {
+	Pos  token.Pos
+	Rule string
+	Msg  string
+}
+
+func (f Finding) String() string {
+	return f.Pos.String() + ": " + f.Msg + " (" + f.Rule + ")"
+}
+
+// Source checks the code, honoring the suppression comments.
+// The code has to parse without errors
+func Source(src string) ([]Finding, error) {
+	p := parser.New(lexer.New(src))
+	prg := p.Parse()
+
+	if errs := p.ErrorList(); len(errs) != 0 {
+		return nil, errs
+	}
+
+	ignored := ignoredRules(src)
+
+	var findings []Finding
+	for _, f := range Check(prg) {
+		rules, ok := ignored[f.Pos.Line]
+		if ok && (len(rules) == 0 || rules[f.Rule]) {
+			continue
+		}
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
+
+// Check checks the program. Findings are ordered by position
+func Check(prg *ast.Program) []Finding {
+	info := resolver.Resolve(prg)
+
+	var findings []Finding
+
+	assigned := assignedNames(prg)
+	for _, id := range info.Undefined {
+		msg := "undefined: " + id.Value
+		if assigned[id] {
+			msg = "assignment to undeclared name " + id.Value
+		}
+		msg += suggest.Hint(info.Suggestions[id])
+		findings = append(findings, Finding{
+			Pos:  id.Token.Pos,
+			Rule: RuleUndefined,
+			Msg:  msg,
+		})
+	}
+
+	for _, d := range info.Decls {
+		if len(d.Uses) == 0 && !strings.HasPrefix(d.Name, "_") {
+			findings = append(findings, Finding{
+				Pos:  d.Ident.Token.Pos,
+				Rule: RuleUnused,
+				Msg:  d.Name + " is declared but not used",
+			})
+		}
+
+		if d.Shadows != nil {
+			findings = append(findings, Finding{
+				Pos:  d.Ident.Token.Pos,
+				Rule: RuleShadow,
+				Msg:  "declaration of " + d.Name + " shadows declaration at " + d.Shadows.Ident.Token.Pos.String(),
+			})
+		}
+	}
+
+	findings = append(findings, unreachable(prg.StNodes)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	})
+
+	return findings
+}
+
+// unreachable reports the first statement after a jump in the statement
+// list and in the nested blocks
+func unreachable(sts []ast.Node) []Finding {
+	var findings []Finding
+
+	for i, st := range sts {
+		switch st := st.(type) {
+		case *ast.WhileSt:
+			findings = append(findings, unreachable(st.Body.StNodes)...)
+		case *ast.ForSt:
+			findings = append(findings, unreachable(st.Body.StNodes)...)
+		case *ast.BlockSt:
+			findings = append(findings, unreachable(st.StNodes)...)
+		case *ast.ReturnSt, *ast.BreakSt, *ast.ContinueSt:
+			if i+1 < len(sts) {
+				return append(findings, Finding{
+					Pos:  ast.Pos(sts[i+1]),
+					Rule: RuleUnreachable,
+					Msg:  "unreachable code",
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// assignedNames finds the identifiers that are assigned to
+func assignedNames(prg *ast.Program) map[*ast.IdentifierEx]bool {
+	assigned := make(map[*ast.IdentifierEx]bool)
+
+	ast.Inspect(prg, func(n ast.Node) bool {
+		if a, ok := n.(*ast.AssignExpr); ok {
+			if id, ok := a.Target.(*ast.IdentifierEx); ok {
+				assigned[id] = true
+			}
+		}
+		return true
+	})
+
+	return assigned
+}
+
+// ignoredRules finds the suppression comments. Maps line to the set of
+// the ignored rules there, empty set means all rules
+func ignoredRules(src string) map[int]map[string]bool {
+	ignored := make(map[int]map[string]bool)
+
+	for t := range lexer.NewWithComments(src).All() {
+		if t.Typ != token.COMMENT {
+			continue
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(t.Literal, "//"))
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+
+		rules := make(map[string]bool)
+		for _, r := range strings.Split(strings.TrimPrefix(text, ignoreDirective), ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				rules[r] = true
+			}
+		}
+		ignored[t.Pos.Line] = rules
+	}
+
+	return ignored
+}
@@ -0,0 +1,125 @@
+package vet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	src := `let a = 1;
+let b = a + c;
+let a = b;
+let _tmp = 0;
+return a;
+a;
+`
+
+	expected := []string{
+		"2:13: undefined: c (undefined)",
+		"3:5: declaration of a shadows declaration at 1:5 (shadow)",
+		"6:1: unreachable code (unreachable)",
+	}
+
+	checkFindings(t, src, expected)
+}
+
+func TestUnused(t *testing.T) {
+	checkFindings(t, "let a = 1;\nlet b = 2;\nb;", []string{"1:5: a is declared but not used (unused)"})
+}
+
+func TestIgnore(t *testing.T) {
+	src := `let a = 1; // vet:ignore
+let b = 2; // vet:ignore shadow
+let c = x; // vet:ignore unused, undefined
+let c = 3; // vet:ignore unused
+c;
+`
+
+	expected := []string{
+		"2:5: b is declared but not used (unused)",
+		"4:5: declaration of c shadows declaration at 3:5 (shadow)",
+	}
+
+	checkFindings(t, src, expected)
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Source("let = 1;"); err == nil {
+		t.Fatal("Expected an error for code that does not parse")
+	}
+}
+
+func checkFindings(t *testing.T, src string, expected []string) {
+	findings, err := Source(src)
+	if err != nil {
+		t.Fatalf("Vet failed: %v", err)
+	}
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected findings\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLoops(t *testing.T) {
+	src := `let xs = 1;
+for (x in xs) {
+	while (x) {
+		break;
+		x;
+	}
+	continue;
+	let y = 1;
+}
+for (_ in xs) {}
+`
+
+	expected := []string{
+		"5:3: unreachable code (unreachable)",
+		"8:2: unreachable code (unreachable)",
+		"8:6: y is declared but not used (unused)",
+	}
+
+	checkFindings(t, src, expected)
+}
+
+func TestAssignment(t *testing.T) {
+	src := `let a = 1;
+a = a + 1;
+b += a;
+let xs = a;
+xs[0] = c;
+`
+
+	expected := []string{
+		"3:1: assignment to undeclared name b (undefined)",
+		"5:9: undefined: c (undefined)",
+	}
+
+	checkFindings(t, src, expected)
+}
+
+func TestSuggestions(t *testing.T) {
+	src := `let count = 1;
+while (count) {
+	let total = count;
+	totl;
+	retrun;
+}
+conut = totl;
+`
+
+	expected := []string{
+		"3:6: total is declared but not used (unused)",
+		"4:2: undefined: totl, did you mean total? (undefined)",
+		"5:2: undefined: retrun, did you mean return? (undefined)",
+		"7:1: assignment to undeclared name conut, did you mean count? (undefined)",
+		"7:9: undefined: totl (undefined)",
+	}
+
+	checkFindings(t, src, expected)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grzkv/m-interpreter/diag"
+	"github.com/grzkv/m-interpreter/vet"
+)
+
+// runVet is *monkey vet [--json] [--no-color] files...*. Without files
+// checks stdin. Exit code is 1 if anything was found
+func runVet(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the findings and errors as a JSON array")
+	noColor := flags.Bool("no-color", false, "print without colors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	code := 0
+	all := []diag.Diagnostic{}
+	for _, name := range names {
+		src, err := readSource(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "vet: %v\n", err)
+			code = 1
+			continue
+		}
+
+		if name == "" {
+			name = "<stdin>"
+		}
+
+		findings, err := vet.Source(src)
+		if err != nil {
+			code = 1
+			if ds, ok := errorDiagnostics(name, err); ok && *asJSON {
+				all = append(all, ds...)
+			} else {
+				reportError("vet", name, src, err, *noColor)
+			}
+			continue
+		}
+
+		ds := vetDiagnostics(name, findings)
+		if len(ds) != 0 {
+			code = 1
+		}
+
+		if *asJSON {
+			all = append(all, ds...)
+		} else {
+			printDiagnostics(os.Stdout, src, ds, useColor(os.Stdout, *noColor))
+		}
+	}
+
+	if *asJSON {
+		if err := diag.WriteJSON(os.Stdout, all); err != nil {
+			fmt.Fprintf(os.Stderr, "vet: %v\n", err)
+			return 1
+		}
+	}
+
+	return code
+}